@@ -1,25 +1,24 @@
 // Generated by gentmpl; *** DO NOT EDIT ***
-// Created: 2025-10-23 19:38:26
+// Created: 2026-10-18 04:54:13
 // Params: no_cache=false, no_go_format=false, asset_manager="embed", func_map="funcMap"
 
 package templates
 
 import (
-	"embed"
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
+
+	"embed"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
-//go:embed "tmpl/flat/footer.tmpl"
-//go:embed "tmpl/flat/header.tmpl"
-//go:embed "tmpl/flat/page1.tmpl"
-//go:embed "tmpl/flat/page2and3.tmpl"
-//go:embed "tmpl/inheritance/base.tmpl"
-//go:embed "tmpl/inheritance/content1.tmpl"
-//go:embed "tmpl/inheritance/content2.tmpl"
-var content embed.FS
-
 // type definitions
 type (
 	// templateEnum is the type of the Templates
@@ -40,27 +39,13 @@ const (
 // number of templates
 const templatesLen = 3
 
-// module variables
-var mTemplates [templatesLen]*template.Template
+// number of pages
+const pagesLen = 5
 
-func file2path(file string) string {
-	const templatesFolder = "tmpl"
-	var path string
-	switch {
-	case len(file) == 0, file[0] == '.', file[0] == filepath.Separator:
-		path = file
-	default:
-		path = filepath.Join(templatesFolder, file)
-	}
-	return path
-}
-
-func files2paths(files []string) []string {
-	paths := make([]string, len(files))
-	for i, file := range files {
-		paths[i] = file2path(file)
-	}
-	return paths
+// String returns the name of the `t` template
+func (t templateEnum) String() string {
+	var names = [...]string{"flat", "inh1", "inh2"}
+	return names[t]
 }
 
 // Files returns the files used by the `t` template
@@ -85,27 +70,16 @@ func (t templateEnum) Files() []string {
 	return astr
 }
 
-// Files returns the files used by the template of the page
-func (page PageEnum) Files() []string {
+// pageTemplate returns the template used by the page
+func (page PageEnum) pageTemplate() templateEnum {
 	// from page to template indexes
 	var p2t = [...]templateEnum{1, 2, 0, 0, 0}
-	// get the template of the page
-	t := p2t[page]
-	return t.Files()
+	return p2t[page]
 }
 
-func InitTemplates() {
-	// init base templates
-	for t := templateEnum(0); t < templatesLen; t++ {
-		files := t.Files()
-		mTemplates[t] = template.Must(template.New(filepath.Base(files[0])).Funcs(funcMap).ParseFS(content, files2paths(files)...))
-	}
-}
-
-// Template returns the template.Template of the page
-func (page PageEnum) Template() *template.Template {
-	var idx = [...]templateEnum{1, 2, 0, 0, 0}
-	return mTemplates[idx[page]]
+// Files returns the files used by the template of the page
+func (page PageEnum) Files() []string {
+	return page.pageTemplate().Files()
 }
 
 // Base returns the template name of the page
@@ -117,13 +91,297 @@ func (page PageEnum) Base() string {
 
 }
 
+// ContentType returns the media type of the page output, to be used as
+// value of the Content-Type header
+func (page PageEnum) ContentType() string {
+	var contentTypes = [...]string{
+		"text/html; charset=utf-8",
+		"text/html; charset=utf-8",
+		"text/html; charset=utf-8",
+		"text/html; charset=utf-8",
+		"text/html; charset=utf-8",
+	}
+	return contentTypes[page]
+}
+
+// names of the pages, in the order of the PageEnum constants
+var pageNames = [pagesLen]string{"Inh1", "Inh2", "Pag1", "Pag2", "Pag3"}
+
+// String returns the name of the page, as defined in the configuration.
+// It returns "PageEnum(n)" if the page is not valid.
+func (page PageEnum) String() string {
+	if !page.IsValid() {
+		return fmt.Sprintf("PageEnum(%d)", page)
+	}
+	return pageNames[page]
+}
+
+// IsValid returns true if the page is one of the PageEnum constants
+func (page PageEnum) IsValid() bool {
+	return page < pagesLen
+}
+
+// AllPages returns all the pages, sorted by name
+func AllPages() []PageEnum {
+	pages := make([]PageEnum, pagesLen)
+	for j := range pages {
+		pages[j] = PageEnum(j)
+	}
+	return pages
+}
+
+// PagesSeq returns an iterator over all the pages, sorted by name, to be used
+// in a range loop (ex: for page := range PagesSeq() {...}) with Go 1.23 or
+// later.
+// The result is assignable to iter.Seq[PageEnum], but is declared
+// without the iter package, so that the package builds with Go 1.22.
+func PagesSeq() func(yield func(PageEnum) bool) {
+	return func(yield func(PageEnum) bool) {
+		for j := 0; j < pagesLen; j++ {
+			if !yield(PageEnum(j)) {
+				return
+			}
+		}
+	}
+}
+
+// UnknownPageError is the error returned parsing the name of a page that
+// is not defined
+type UnknownPageError struct {
+	Name string // name of the page
+}
+
+func (e *UnknownPageError) Error() string {
+	return fmt.Sprintf("unknown page %q", e.Name)
+}
+
+// ParsePage returns the page with the given name, as returned by String.
+// It returns an *UnknownPageError if no page has the given name.
+func ParsePage(name string) (PageEnum, error) {
+	for j, pageName := range pageNames {
+		if pageName == name {
+			return PageEnum(j), nil
+		}
+	}
+	return 0, &UnknownPageError{Name: name}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// The page is encoded with its name, that does not change when other pages
+// are added or removed.
+func (page PageEnum) MarshalText() ([]byte, error) {
+	if !page.IsValid() {
+		return nil, fmt.Errorf("cannot marshal invalid page %s", page)
+	}
+	return []byte(pageNames[page]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It returns an *UnknownPageError if no page has the given name.
+func (page *PageEnum) UnmarshalText(text []byte) error {
+	p, err := ParsePage(string(text))
+	if err != nil {
+		return err
+	}
+	*page = p
+	return nil
+}
+
+// Value implements the driver.Valuer interface: the page is stored with its
+// name.
+func (page PageEnum) Value() (driver.Value, error) {
+	text, err := page.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Scan implements the sql.Scanner interface: the page is read from its name.
+// It returns an *UnknownPageError if no page has the given name.
+func (page *PageEnum) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return page.UnmarshalText([]byte(src))
+	case []byte:
+		return page.UnmarshalText(src)
+	}
+	return fmt.Errorf("cannot scan %T into PageEnum", src)
+}
+
+// PageInfo contains the information about a page
+type PageInfo struct {
+	Name        string   // name of the page
+	Template    string   // name of the template of the page
+	Base        string   // name of the executed template, or "" if the template is executed
+	Files       []string // files of the template
+	ContentType string   // media type of the page output
+}
+
+// Info returns the information about the page
+func (page PageEnum) Info() PageInfo {
+	t := page.pageTemplate()
+	return PageInfo{
+		Name:        page.String(),
+		Template:    t.String(),
+		Base:        page.Base(),
+		Files:       t.Files(),
+		ContentType: page.ContentType(),
+	}
+}
+
+//go:embed "tmpl/flat/footer.tmpl"
+//go:embed "tmpl/flat/header.tmpl"
+//go:embed "tmpl/flat/page1.tmpl"
+//go:embed "tmpl/flat/page2and3.tmpl"
+//go:embed "tmpl/inheritance/base.tmpl"
+//go:embed "tmpl/inheritance/content1.tmpl"
+//go:embed "tmpl/inheritance/content2.tmpl"
+var content embed.FS
+
+// templateCache contains the created templates
+type templateCache struct {
+	tmpls [templatesLen]*template.Template
+	// pools of clones of the templates, used by ExecuteContext to bind
+	// the "context" template function
+	pools [templatesLen]sync.Pool
+}
+
+// module variables
+var mTemplates atomic.Pointer[templateCache]
+
+func file2path(file string) string {
+	const templatesFolder = "tmpl"
+	var path string
+	switch {
+	case len(file) == 0, file[0] == '.', file[0] == filepath.Separator:
+		path = file
+	default:
+		path = filepath.Join(templatesFolder, file)
+	}
+	return path
+}
+
+func files2paths(files []string) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file2path(file)
+	}
+	return paths
+}
+
+// parse creates the `t` template, loading and parsing its files
+func (t templateEnum) parse() (*template.Template, error) {
+	files := t.Files()
+	return template.New(filepath.Base(files[0])).Funcs(funcMap).Funcs(contextFuncMap(context.Background())).ParseFS(content, files2paths(files)...)
+}
+
+// create creates the `t` template of the cache.
+func (cache *templateCache) create(t templateEnum) error {
+	tmpl, err := t.parse()
+	if err != nil {
+		return fmt.Errorf("template %s: %w", t, err)
+	}
+	// the clones are created from a copy of the template that is never
+	// executed, as required by html/template
+	master, err := tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("template %s: %w", t, err)
+	}
+	cache.pools[t].New = func() any {
+		clone, _ := master.Clone()
+		return clone
+	}
+	cache.tmpls[t] = tmpl
+	return nil
+}
+
+// parseTemplates creates all the templates.
+// It returns an error that joins the errors of every template that cannot
+// be created.
+func parseTemplates() (*templateCache, error) {
+	var (
+		cache templateCache
+		errs  []error
+	)
+	for t := templateEnum(0); t < templatesLen; t++ {
+		if err := cache.create(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &cache, nil
+}
+
+// LoadTemplates creates all the templates, loading and parsing their files.
+// It returns an error that joins the errors of every template that cannot
+// be created: in that case no template is loaded.
+func LoadTemplates() error {
+	tmpls, err := parseTemplates()
+	if err != nil {
+		return err
+	}
+	mTemplates.Store(tmpls)
+	return nil
+}
+
+// InitTemplates creates all the templates.
+// It panics if any template cannot be created.
+func InitTemplates() {
+	if err := LoadTemplates(); err != nil {
+		panic(err)
+	}
+}
+
+// Reload creates again all the templates, loading and parsing their files,
+// and atomically replaces the current templates with the new ones.
+// Pages executed concurrently use either the old or the new templates.
+// If any template cannot be created, the current templates are kept and the
+// returned error joins the errors of every failing template.
+func Reload() error {
+	return LoadTemplates()
+}
+
+// Template returns the template of the page.
+// It returns nil if the templates are not loaded.
+func (page PageEnum) Template() *template.Template {
+	tmpl, _ := page.template()
+	return tmpl
+}
+
+// template returns the template of the page, or
+// ErrTemplatesNotLoaded if the templates are not loaded.
+func (page PageEnum) template() (*template.Template, error) {
+	var idx = [...]templateEnum{1, 2, 0, 0, 0}
+	cache := mTemplates.Load()
+	if cache == nil {
+		return nil, ErrTemplatesNotLoaded
+	}
+	t := idx[page]
+	return cache.tmpls[t], nil
+}
+
+// ErrTemplatesNotLoaded is returned executing a page before the templates
+// are loaded.
+var ErrTemplatesNotLoaded = errors.New("templates not loaded: LoadTemplates or InitTemplates must be called before executing a page")
+
 // Execute applies a parsed page template to the specified data object,
 // writing the output to wr.
 // If an error occurs executing the template or writing its output, execution
 // stops, but partial results may already have been written to the output writer.
 // A template may be executed safely in parallel.
 func (page PageEnum) Execute(wr io.Writer, data interface{}) error {
-	tmpl := page.Template()
+	tmpl, err := page.template()
+	if err != nil {
+		return err
+	}
+	return page.execute(tmpl, wr, data)
+}
+
+// execute applies the tmpl template of the page to the data object.
+func (page PageEnum) execute(tmpl *template.Template, wr io.Writer, data any) error {
 	name := page.Base()
 	if name != "" {
 		return tmpl.ExecuteTemplate(wr, name, data)
@@ -131,6 +389,136 @@ func (page PageEnum) Execute(wr io.Writer, data interface{}) error {
 	return tmpl.Execute(wr, data)
 }
 
+// contextWriter is an io.Writer that stops writing once the context is done.
+type contextWriter struct {
+	ctx context.Context
+	wr  io.Writer
+}
+
+// Write writes p to the underlying writer, unless the context is done.
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.wr.Write(p)
+}
+
+// contextFuncMap returns the template functions bound to the context.
+// The "context" function overrides a function with the same name defined in
+// funcMap.
+func contextFuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"context": func() context.Context { return ctx },
+	}
+}
+
+// contextTemplate returns a template of the page whose "context" function
+// returns ctx, and the function to call when the template is no more used.
+func (page PageEnum) contextTemplate(ctx context.Context) (*template.Template, func(), error) {
+	var idx = [...]templateEnum{1, 2, 0, 0, 0}
+	cache := mTemplates.Load()
+	if cache == nil {
+		return nil, nil, ErrTemplatesNotLoaded
+	}
+	t := idx[page]
+	pool := &cache.pools[t]
+	tmpl := pool.Get().(*template.Template)
+	tmpl.Funcs(contextFuncMap(ctx))
+	return tmpl, func() {
+		// do not keep a reference to the context in the pool
+		tmpl.Funcs(contextFuncMap(context.Background()))
+		pool.Put(tmpl)
+	}, nil
+}
+
+// ExecuteContext applies a parsed page template to the specified data object,
+// writing the output to wr, as Execute does.
+// Once the context is done, nothing more is written to wr and the execution
+// stops returning ctx.Err(). Note that the context is checked only when the
+// template writes its output.
+//
+// The context is available to the functions of funcMap through the
+// "context" template function, that returns ctx (or context.Background() if
+// the page is rendered with Execute). Example:
+//
+//	{{ userName context .UserID }}
+//
+// where userName is a function of funcMap with a context.Context
+// argument.
+func (page PageEnum) ExecuteContext(ctx context.Context, wr io.Writer, data any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tmpl, release, err := page.contextTemplate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = page.execute(tmpl, &contextWriter{ctx: ctx, wr: wr}, data)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// maxPooledBufferSize is the capacity above which a buffer is not put back
+// into the pool, so that a single big page does not keep its memory alive.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool is the pool of the buffers used to render the pages.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer puts the buffer back into the pool.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// Render applies a parsed page template to the specified data object,
+// writing the output to wr.
+// The page is rendered into a buffer, and the output is written to wr only
+// if no error occurs executing the template: in case of error nothing is
+// written to wr.
+// A template may be executed safely in parallel.
+func (page PageEnum) Render(wr io.Writer, data any) error {
+	tmpl, err := page.template()
+	if err != nil {
+		return err
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := page.execute(tmpl, buf, data); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(wr)
+	return err
+}
+
+// RenderString applies a parsed page template to the specified data object,
+// returning the output as a string.
+func (page PageEnum) RenderString(data any) (string, error) {
+	tmpl, err := page.template()
+	if err != nil {
+		return "", err
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := page.execute(tmpl, buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 /*
 func main(){
 	InitTemplates()
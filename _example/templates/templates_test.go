@@ -2,6 +2,7 @@ package templates
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -65,3 +66,33 @@ func Test_file2path(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkExecute(b *testing.B) {
+	InitTemplates()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := PagePag1.Execute(io.Discard, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	InitTemplates()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := PagePag1.Render(io.Discard, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderString(b *testing.B) {
+	InitTemplates()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := PagePag1.RenderString(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mmbros/gentmpl/internal/cmdline"
+	"github.com/mmbros/gentmpl/internal/config"
+	"github.com/mmbros/gentmpl/run"
+)
+
+// writeDiagnostics writes the diagnostics to w, one per line.
+// Each diagnostic is prefixed by the position of the configuration key that
+// causes it, or by the path of the configuration file if the position is not
+// known.
+// It returns true if any of the diagnostics is an error.
+func writeDiagnostics(w io.Writer, path string, diags []run.Diagnostic) bool {
+	failed := false
+	for _, diag := range diags {
+		if diag.Pos.IsValid() {
+			fmt.Fprintln(w, diag)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", path, diag)
+		}
+		if diag.Severity == run.SeverityError {
+			failed = true
+		}
+	}
+	return failed
+}
+
+// cmdCheck loads the configuration file and writes to w every problem found
+// in it, including the warnings found loading it, without generating the
+// package.
+// It returns true if any of the problems is an error.
+func cmdCheck(args *cmdline.Args, w io.Writer) (bool, error) {
+	cfg, err := config.Parse(args)
+	if err != nil {
+		return false, err
+	}
+	diags := append(cfg.Warnings, cfg.Validate()...)
+	return writeDiagnostics(w, args.Config(), diags), nil
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/internal/cmdline"
+)
+
+func TestCmdCheck(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		cfgPath  = filepath.Join(dir, "gentmpl.conf")
+		tmplPath = filepath.Join(dir, "tmpl", "page.tmpl")
+	)
+	if err := os.MkdirAll(filepath.Dir(tmplPath), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tmplPath, []byte(`{{define "page"}}page{{end}}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	header := "template_base_dir = " + `"` + filepath.Join(dir, "tmpl") + `"` + "\n"
+
+	tests := []struct {
+		name       string
+		config     string
+		wantFailed bool
+		wantLog    []string
+	}{
+		{
+			name: "ok",
+			config: header + `[templates]
+t = ["page.tmpl"]
+[pages]
+P = {template="t", base="page"}
+`,
+		},
+		{
+			name: "warnings",
+			config: header + `[templates]
+t = ["page.tmpl"]
+unused = ["page.tmpl"]
+[pages]
+P = {template="t", base="page"}
+`,
+			wantLog: []string{
+				cfgPath + ":4:1: warning: template unused is not used by any page",
+			},
+		},
+		{
+			name: "deprecated key",
+			config: header + `text_remplate = false
+[templates]
+t = ["page.tmpl"]
+[pages]
+P = {template="t", base="page"}
+`,
+			wantLog: []string{
+				cfgPath + `:2:1: warning: key "text_remplate" is deprecated: use "text_template"`,
+			},
+		},
+		{
+			name:       "no pages",
+			config:     header,
+			wantFailed: true,
+			wantLog: []string{
+				cfgPath + ": no pages found",
+			},
+		},
+		{
+			name: "errors",
+			config: header + `[templates]
+t = ["page.tmpl"]
+[pages]
+P = {template="t", base="page"}
+Q = {template="t2"}
+R = {template="t", base="page", method="GET"}
+`,
+			wantFailed: true,
+			wantLog: []string{
+				cfgPath + `:6:1: page Q: template "t2" not defined`,
+				cfgPath + `:7:1: page R: method "GET" without route`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(cfgPath, []byte(tt.config), 0666); err != nil {
+				t.Fatal(err)
+			}
+			args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+			if err := args.Parse([]string{"check", "-c", cfgPath}); err != nil {
+				t.Fatal(err)
+			}
+
+			var log strings.Builder
+			failed, err := cmdCheck(args, &log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("failed: got %v, want %v", failed, tt.wantFailed)
+			}
+			var gotLog []string
+			if s := strings.TrimSpace(log.String()); s != "" {
+				gotLog = strings.Split(s, "\n")
+			}
+			if strings.Join(gotLog, "\n") != strings.Join(tt.wantLog, "\n") {
+				t.Errorf("log:\ngot  %q\nwant %q", gotLog, tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmbros/gentmpl/internal/config"
+	"github.com/mmbros/gentmpl/internal/diff"
+	"github.com/mmbros/gentmpl/run"
+)
+
+// createdPrefix is the start of the line of the header of the generated
+// package with the creation time.
+const createdPrefix = "// Created: "
+
+// stripCreated returns the text without the first line with the creation
+// time, so that two generations of the package can be compared.
+func stripCreated(text string) string {
+	start := 0
+	for start < len(text) {
+		end := strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start + 1
+		}
+		if strings.HasPrefix(text[start:end], createdPrefix) {
+			return text[:start] + text[end:]
+		}
+		start = end
+	}
+	return text
+}
+
+// cmdCheckDiff generates the package in memory and writes to w the unified
+// diff between the output, a file (and the golden test file, if any) or the
+// files of a directory, and the generated package, ignoring the creation
+// time.
+// It returns true if the output is up to date.
+func cmdCheckDiff(cfg *config.Config, w io.Writer) (bool, error) {
+	path := cfg.OutputFile
+
+	var (
+		files []run.GeneratedFile // the Name is the path of the file
+		stale []string
+	)
+	if config.IsOutputDir(path) {
+		gen, err := cfg.PackageFiles()
+		if err != nil {
+			return false, err
+		}
+		for _, f := range gen {
+			files = append(files, run.GeneratedFile{Name: filepath.Join(path, f.Name), Content: f.Content})
+		}
+		if stale, err = run.StaleFiles(path, gen); err != nil {
+			return false, err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := cfg.WritePackage(&buf); err != nil {
+			return false, err
+		}
+		files = append(files, run.GeneratedFile{Name: path, Content: buf.Bytes()})
+		if cfg.GoldenTests {
+			var test bytes.Buffer
+			if err := cfg.WriteGoldenTest(&test); err != nil {
+				return false, err
+			}
+			files = append(files, run.GeneratedFile{Name: goldenTestPath(path), Content: test.Bytes()})
+		}
+	}
+
+	upToDate := true
+	compare := func(path, generated string) error {
+		current, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		d := diff.Unified(path, path+" (generated)", stripCreated(string(current)), stripCreated(generated))
+		if d != "" {
+			upToDate = false
+			_, err = io.WriteString(w, d)
+		}
+		return err
+	}
+	for _, f := range files {
+		if err := compare(f.Name, string(f.Content)); err != nil {
+			return false, err
+		}
+	}
+	// the stale files would be removed
+	for _, p := range stale {
+		if err := compare(p, ""); err != nil {
+			return false, err
+		}
+	}
+	return upToDate, nil
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/internal/config"
+	"github.com/mmbros/gentmpl/run"
+)
+
+func TestStripCreated(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"// header\n// Created: 2001-09-09 01:46:40\npackage p\n", "// header\npackage p\n"},
+		{"// Created: 2001-09-09 01:46:40", ""},
+		{"package p\n// Created: a\n// Created: b\n", "package p\n// Created: b\n"},
+		{"package p\n", "package p\n"},
+	}
+	for _, tt := range tests {
+		if got := stripCreated(tt.text); got != tt.want {
+			t.Errorf("stripCreated(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCmdCheckDiff(t *testing.T) {
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "tmpl", "page.tmpl")
+	if err := os.MkdirAll(filepath.Dir(tmplPath), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tmplPath, []byte(`{{define "page"}}page{{end}}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	newConfig := func(output string) *config.Config {
+		return &config.Config{
+			Context: run.Context{
+				TemplateBaseDir: filepath.Join(dir, "tmpl"),
+				Templates:       map[string][]string{"t": {"page.tmpl"}},
+				Pages:           map[string]run.Page{"P": {Template: "t", Base: "page"}},
+			},
+			OutputFile: output,
+		}
+	}
+
+	t.Run("file", func(t *testing.T) {
+		cfg := newConfig(filepath.Join(dir, "templates.go"))
+
+		// missing output file
+		var out strings.Builder
+		upToDate, err := cmdCheckDiff(cfg, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if upToDate || !strings.HasPrefix(out.String(), "--- "+cfg.OutputFile+"\n") {
+			t.Errorf("missing file: upToDate = %v, diff:\n%s", upToDate, out.String())
+		}
+
+		// the creation time is ignored
+		var buf bytes.Buffer
+		if err := cfg.WritePackage(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(cfg.OutputFile, buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("SOURCE_DATE_EPOCH", "0")
+		out.Reset()
+		if upToDate, err = cmdCheckDiff(cfg, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !upToDate || out.Len() > 0 {
+			t.Errorf("up to date: upToDate = %v, diff:\n%s", upToDate, out.String())
+		}
+
+		cfg.Pages["Q"] = run.Page{Template: "t", Base: "page"}
+		out.Reset()
+		if upToDate, err = cmdCheckDiff(cfg, &out); err != nil {
+			t.Fatal(err)
+		}
+		if upToDate || !strings.Contains(out.String(), "\n+\tPageQ\n") {
+			t.Errorf("changed: upToDate = %v, diff:\n%s", upToDate, out.String())
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		output := filepath.Join(dir, "templates")
+		cfg := newConfig(output)
+		if err := cfg.WritePackageDir(output); err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		upToDate, err := cmdCheckDiff(cfg, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !upToDate || out.Len() > 0 {
+			t.Errorf("up to date: upToDate = %v, diff:\n%s", upToDate, out.String())
+		}
+
+		stale := filepath.Join(output, "old_gen.go")
+		if err := os.WriteFile(stale, []byte("// Generated by gentmpl; DO NOT EDIT\npackage templates\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if upToDate, err = cmdCheckDiff(cfg, &out); err != nil {
+			t.Fatal(err)
+		}
+		want := "--- " + stale + "\n+++ " + stale + " (generated)\n@@ -1,2 +0,0 @@\n"
+		if upToDate || !strings.HasPrefix(out.String(), want) {
+			t.Errorf("stale file: upToDate = %v, diff:\n%s", upToDate, out.String())
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmbros/gentmpl/internal/cmdline"
+	"github.com/mmbros/gentmpl/internal/config"
+)
+
+func TestCmdInit(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		cfgPath = filepath.Join(dir, "gentmpl.conf")
+		tmplDir = filepath.Join(dir, "tmpl")
+	)
+	files := map[string]string{
+		"layout.tmpl": `<body>{{template "main" .}}</body>`,
+		"home.tmpl":   `{{define "main"}}home{{end}}`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(tmplDir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmplDir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+	if err := args.Parse([]string{"init", "-b", tmplDir, "-o", cfgPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmdInit(args); err != nil {
+		t.Fatal(err)
+	}
+
+	// the written configuration is valid
+	cfg, err := config.FromFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := cfg.Validate(); len(diags) > 0 {
+		t.Errorf("Validate() = %v, want no diagnostics", diags)
+	}
+	want := []string{"layout.tmpl", "home.tmpl"}
+	if got := cfg.Templates["layout"]; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("template layout = %q, want %q", got, want)
+	}
+	if page := cfg.Pages["Layout"]; page.Template != "layout" {
+		t.Errorf("page Layout template = %q, want %q", page.Template, "layout")
+	}
+
+	// an existing file is not overwritten
+	if err := cmdInit(args); err == nil {
+		t.Errorf("expected error writing an existing file; no error found")
+	}
+}
@@ -2,14 +2,17 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/mmbros/gentmpl/internal/cmdline"
 	"github.com/mmbros/gentmpl/internal/config"
 	"github.com/mmbros/gentmpl/internal/version"
+	"github.com/mmbros/gentmpl/run"
 )
 
 // Run parses the command line arguments and executes the corrisponding command:
@@ -17,8 +20,15 @@ import (
 //   - PrintVersion: print version information
 //   - CreateConfig: generate the package based on the provided configuration parameters
 //   - CreatePackage: generate the template package
+//   - Check: report the problems of the configuration file
+//   - Init: create the configuration file from the templates files
+//   - Watch: generate the template package on every change of the files
+//   - CheckDiff: check that the output file is up to date
 //
-// It returns the code that should be used for os.Exit.
+// It returns the code that should be used for os.Exit: 0 in case of success,
+// 1 if the package cannot be generated or the configuration has errors, 2 in
+// case of invalid arguments or configuration file, 3 if the output file is
+// not up to date.
 func Run(appName string) int {
 
 	args := cmdline.NewArgs(appName, flag.ExitOnError)
@@ -48,6 +58,21 @@ func Run(appName string) int {
 		return 0
 	}
 
+	if args.Init() {
+		err := cmdInit(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		return 0
+	}
+
+	if args.CheckDiff() && args.OutputFile() == "" {
+		fmt.Fprintf(os.Stderr, "The -check option needs the output file given with the -o option.\n"+
+			"Try '%s -h' for more information.\n", appName)
+		return 2
+	}
+
 	// check config file exists
 	if _, err := os.Stat(args.Config()); os.IsNotExist(err) {
 		// print error  message and hint
@@ -56,12 +81,47 @@ func Run(appName string) int {
 		return 2
 	}
 
+	if args.Check() {
+		failed, err := cmdCheck(args, os.Stderr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	}
+
+	if args.Watch() {
+		err := cmdWatch(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		return 0
+	}
+
 	// read config file
 	cfg, err := config.Parse(args)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return 2
 	}
+	writeDiagnostics(os.Stderr, args.Config(), cfg.Warnings)
+
+	if args.CheckDiff() {
+		upToDate, err := cmdCheckDiff(cfg, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		if !upToDate {
+			fmt.Fprintf(os.Stderr, "%s is not up to date\n", cfg.OutputFile)
+			return 3
+		}
+		return 0
+	}
 
 	// generate the package
 	err = cmdGenPackage(cfg)
@@ -92,15 +152,84 @@ func writeOutput(path string, fn func(io.Writer) error) error {
 	return fn(w)
 }
 
+// goldenTestPath returns the path of the golden test file written with the
+// output file, in the same folder.
+func goldenTestPath(output string) string {
+	return filepath.Join(filepath.Dir(output), run.GoldenTestFile)
+}
+
 // cmdGenPackage generate the package based on the provided configuration
 // parameters.
+// If the output is a directory, the package is split in many files.
+// If golden_tests is true, the golden test file is also written.
 func cmdGenPackage(cfg *config.Config) error {
 	ctx := cfg.Context
-	return writeOutput(cfg.OutputFile, ctx.WritePackage)
+	if config.IsOutputDir(cfg.OutputFile) {
+		return ctx.WritePackageDir(cfg.OutputFile)
+	}
+	if ctx.GoldenTests && cfg.OutputFile == "" {
+		return errors.New("golden_tests needs the output file given with the -o option")
+	}
+	if err := writeOutput(cfg.OutputFile, ctx.WritePackage); err != nil {
+		return err
+	}
+	if ctx.GoldenTests {
+		return writeOutput(goldenTestPath(cfg.OutputFile), ctx.WriteGoldenTest)
+	}
+	return nil
+}
+
+// outputFormat returns the format of the configuration file to write: the
+// format flag or, if missing, the format of the extension of the output file.
+func outputFormat(args *cmdline.Args) (config.Format, error) {
+	format, err := config.ParseFormat(args.Format())
+	if err != nil {
+		return "", err
+	}
+	if format == "" {
+		format = config.FormatOf(args.OutputFile())
+	}
+	return format, nil
+}
+
+// writeConfig writes the Context as a configuration file of the given format.
+func writeConfig(path string, format config.Format, ctx *run.Context) error {
+	if format == config.FormatJSON {
+		return writeOutput(path, ctx.WriteConfigJSON)
+	}
+	return writeOutput(path, ctx.WriteConfig)
+}
+
+// cmdInit creates a configuration file proposing the templates and the pages
+// found scanning the templates files in the base directory.
+// An existing output file is not overwritten.
+func cmdInit(args *cmdline.Args) error {
+	format, err := outputFormat(args)
+	if err != nil {
+		return err
+	}
+	if path := args.OutputFile(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("configuration file %q already exists", path)
+		}
+	}
+
+	ctx := &run.Context{TemplateBaseDir: args.TemplateBaseDir()}
+	if err := ctx.Scaffold(); err != nil {
+		return err
+	}
+	return writeConfig(args.OutputFile(), format, ctx)
 }
 
 // cmdGenConfig generate a demo configuration file for the gentmpl tool.
+// The file format is given by the format flag or, if missing, by the
+// extension of the output file.
 func cmdGenConfig(args *cmdline.Args) error {
+	format, err := outputFormat(args)
+	if err != nil {
+		return err
+	}
+
 	const text = `[templates]
 flat = ["flat/footer.tmpl", "flat/header.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl"]
 inhbase = ["inheritance/base.tmpl"]
@@ -118,6 +247,5 @@ Inh2 = {template="inh2"}
 		return err
 	}
 
-	ctx := cfg.Context
-	return writeOutput(args.OutputFile(), ctx.WriteConfig)
+	return writeConfig(args.OutputFile(), format, &cfg.Context)
 }
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/internal/config"
+	"github.com/mmbros/gentmpl/run"
+)
+
+func TestCmdGenPackageGoldenTests(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tmpl"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tmpl", "page.tmpl"), []byte("page"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Context: run.Context{
+			TemplateBaseDir: filepath.Join(dir, "tmpl"),
+			GoldenTests:     true,
+			Templates:       map[string][]string{"t": {"page.tmpl"}},
+			Pages:           map[string]run.Page{"P": {Template: "t"}},
+		},
+	}
+	if err := cmdGenPackage(cfg); err == nil || !strings.Contains(err.Error(), "-o option") {
+		t.Errorf("cmdGenPackage() to stdout: expected -o option error, found %v", err)
+	}
+
+	cfg.OutputFile = filepath.Join(dir, "templates.go")
+	if err := cmdGenPackage(cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"templates.go", run.GoldenTestFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected file %s, found error %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/mmbros/gentmpl/internal/cmdline"
+	"github.com/mmbros/gentmpl/internal/config"
+)
+
+// watchInterval is the interval between two checks of the watched files.
+const watchInterval = 500 * time.Millisecond
+
+// fileState is the state of a watched file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot returns the current state of the files.
+// The files that do not exist are not included.
+func snapshot(paths []string) map[string]fileState {
+	states := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		states[path] = fileState{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return states
+}
+
+// equalStates returns true if the two snapshots are equal.
+func equalStates(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, sa := range a {
+		sb, ok := b[path]
+		if !ok || sa.size != sb.size || !sa.modTime.Equal(sb.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// watcher generates the package whenever the configuration file, the files
+// included by it or the templates files change.
+type watcher struct {
+	args *cmdline.Args
+	log  io.Writer
+
+	// watched files: the configuration files and the templates files
+	files []string
+	// state of the watched files at the last generation
+	states map[string]fileState
+
+	// last configuration loaded, used to expand again the glob patterns
+	// of the templates
+	cfg *config.Config
+	// templates files of the last configuration loaded, nil if they
+	// cannot be resolved
+	matched []string
+}
+
+func newWatcher(args *cmdline.Args, log io.Writer) *watcher {
+	return &watcher{
+		args:  args,
+		log:   log,
+		files: []string{args.Config()},
+	}
+}
+
+// generate loads the configuration file and writes the package.
+// The list of the watched files is updated, unless the configuration file
+// cannot be loaded.
+// The output file and the golden test file, or the files of the output
+// directory, are written only if the package is generated without errors.
+func (w *watcher) generate() error {
+	cfg, err := config.Parse(w.args)
+	if err != nil {
+		return err
+	}
+	writeDiagnostics(w.log, w.args.Config(), cfg.Warnings)
+	files, err := cfg.TemplateFiles()
+	if err == nil {
+		w.files = append([]string{w.args.Config()}, cfg.IncludedFiles...)
+		w.files = append(w.files, files...)
+	}
+	w.cfg = cfg
+	w.matched = files
+
+	if config.IsOutputDir(cfg.OutputFile) {
+		return cfg.WritePackageDir(cfg.OutputFile)
+	}
+	var buf, test bytes.Buffer
+	if err := cfg.WritePackage(&buf); err != nil {
+		return err
+	}
+	if cfg.GoldenTests {
+		if err := cfg.WriteGoldenTest(&test); err != nil {
+			return err
+		}
+	}
+	write := func(path string, p []byte) error {
+		return writeOutput(path, func(wr io.Writer) error {
+			_, err := wr.Write(p)
+			return err
+		})
+	}
+	if err := write(cfg.OutputFile, buf.Bytes()); err != nil {
+		return err
+	}
+	if cfg.GoldenTests {
+		return write(goldenTestPath(cfg.OutputFile), test.Bytes())
+	}
+	return nil
+}
+
+// run generates the package and prints the result to the log.
+func (w *watcher) run() {
+	files := w.files
+	states := snapshot(files)
+
+	err := w.generate()
+
+	if !slices.Equal(files, w.files) {
+		// the watched files are changed
+		states = snapshot(w.files)
+	}
+	w.states = states
+
+	now := time.Now().Format("15:04:05")
+	if err != nil {
+		fmt.Fprintf(w.log, "[%s] error: %s\n", now, err.Error())
+		return
+	}
+	fmt.Fprintf(w.log, "[%s] generated %s\n", now, w.args.OutputFile())
+}
+
+// poll checks the watched files and generates the package if any of them
+// is changed, created or removed since the last generation, or if a file
+// matching a glob pattern of the templates is created or removed.
+func (w *watcher) poll() {
+	if !equalStates(snapshot(w.files), w.states) || w.matchesChanged() {
+		w.run()
+	}
+}
+
+// matchesChanged returns true if the templates files, expanding again the
+// glob patterns of the templates, are not the ones of the last generation
+// (ex: a new file matching "partials/**/*.tmpl").
+func (w *watcher) matchesChanged() bool {
+	if w.cfg == nil {
+		return false
+	}
+	files, err := w.cfg.TemplateFiles()
+	if err != nil {
+		files = nil
+	}
+	return !slices.Equal(files, w.matched)
+}
+
+// watch generates the package, and then checks the watched files every
+// interval until the context is done.
+func (w *watcher) watch(ctx context.Context, interval time.Duration) {
+	w.run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// cmdWatch generates the package, and generates it again whenever the
+// configuration file or the templates files change, until interrupted.
+func cmdWatch(args *cmdline.Args) error {
+	if args.OutputFile() == "" {
+		return errors.New("watch command: the output file must be given with the -o option")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	newWatcher(args, os.Stderr).watch(ctx, watchInterval)
+	return nil
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/internal/cmdline"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		cfgPath  = filepath.Join(dir, "gentmpl.conf")
+		outPath  = filepath.Join(dir, "templates.go")
+		tmplPath = filepath.Join(dir, "tmpl", "page.tmpl")
+	)
+	config := "template_base_dir = " + `"` + filepath.Join(dir, "tmpl") + `"` + `
+[templates]
+t = ["page.tmpl"]
+[pages]
+P = {template="t", base="page"}
+`
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(cfgPath, config)
+	writeFile(tmplPath, `{{define "page"}}page{{end}}`)
+
+	args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+	if err := args.Parse([]string{"watch", "-c", cfgPath, "-o", outPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	var log strings.Builder
+	w := newWatcher(args, &log)
+
+	// lastLog returns the last line of the log and resets it
+	lastLog := func() string {
+		lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+		log.Reset()
+		return lines[len(lines)-1]
+	}
+
+	// first generation
+	w.run()
+	if s := lastLog(); !strings.Contains(s, "generated") {
+		t.Fatalf("first generation: unexpected log %q", s)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("first generation: %s", err)
+	}
+	if len(w.files) != 2 {
+		t.Errorf("watched files: got %v, want config and template files", w.files)
+	}
+
+	// nothing changed
+	w.poll()
+	if log.Len() != 0 {
+		t.Errorf("no changes: unexpected log %q", log.String())
+	}
+
+	// template file changed, with an error
+	writeFile(tmplPath, `{{define "page2"}}page{{end}}`)
+	w.poll()
+	if s := lastLog(); !strings.Contains(s, `error: `+cfgPath+`:5:1: page P: base "page" not defined`) {
+		t.Errorf("template changed: unexpected log %q", s)
+	}
+
+	// template file fixed
+	writeFile(tmplPath, `{{define "page"}}new page{{end}}`)
+	w.poll()
+	if s := lastLog(); !strings.Contains(s, "generated") {
+		t.Errorf("template fixed: unexpected log %q", s)
+	}
+
+	// config file changed: a new template file is watched
+	writeFile(filepath.Join(dir, "tmpl", "other.tmpl"), `{{define "other"}}other{{end}}`)
+	writeFile(cfgPath, config+`O = {template="o", base="other"}
+[templates.o]
+`)
+	w.poll()
+	if s := lastLog(); !strings.Contains(s, "error:") {
+		t.Errorf("invalid config: unexpected log %q", s)
+	}
+	writeFile(cfgPath, strings.Replace(config, `t = ["page.tmpl"]`, `t = ["page.tmpl"]
+o = ["other.tmpl"]`, 1)+`O = {template="o", base="other"}
+`)
+	w.poll()
+	if s := lastLog(); !strings.Contains(s, "generated") {
+		t.Errorf("config changed: unexpected log %q", s)
+	}
+	if len(w.files) != 3 {
+		t.Errorf("watched files: got %v, want config and 2 template files", w.files)
+	}
+}
+
+func TestWatcherGlob(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		cfgPath = filepath.Join(dir, "gentmpl.conf")
+		outPath = filepath.Join(dir, "templates.go")
+		newPath = filepath.Join(dir, "tmpl", "partials", "sub", "new.tmpl")
+	)
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(cfgPath, "template_base_dir = "+`"`+filepath.Join(dir, "tmpl")+`"`+`
+[templates]
+t = ["page.tmpl", "partials/**/*.tmpl"]
+[pages]
+P = {template="t", base="page"}
+`)
+	writeFile(filepath.Join(dir, "tmpl", "page.tmpl"), `{{define "page"}}page{{end}}`)
+	writeFile(filepath.Join(dir, "tmpl", "partials", "header.tmpl"), `{{define "header"}}header{{end}}`)
+
+	args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+	if err := args.Parse([]string{"watch", "-c", cfgPath, "-o", outPath}); err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	w := newWatcher(args, &log)
+	w.run()
+	if len(w.files) != 3 {
+		t.Fatalf("watched files: got %v, want config and 2 template files", w.files)
+	}
+
+	// generated checks that the package is generated with or without the
+	// new file
+	generated := func(what string, withNew bool) {
+		t.Helper()
+		if !strings.Contains(log.String(), "generated") {
+			t.Fatalf("%s: unexpected log %q", what, log.String())
+		}
+		log.Reset()
+		out, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(out), "partials/sub/new.tmpl"); got != withNew {
+			t.Errorf("%s: new file in the package = %v, want %v", what, got, withNew)
+		}
+	}
+	log.Reset()
+
+	// new file matching the pattern
+	writeFile(newPath, `{{define "new"}}new{{end}}`)
+	w.poll()
+	generated("new file", true)
+	if len(w.files) != 4 {
+		t.Errorf("watched files: got %v, want config and 3 template files", w.files)
+	}
+
+	// nothing changed
+	w.poll()
+	if log.Len() != 0 {
+		t.Errorf("no changes: unexpected log %q", log.String())
+	}
+
+	// file matching the pattern removed
+	if err := os.Remove(newPath); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	generated("removed file", false)
+}
@@ -3,27 +3,39 @@ package cmdline
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 )
 
 const (
 	// name of the command line parameters
 	clBaseDir   = "b"
+	clCheckDiff = "check"
 	clConfig    = "c"
 	clDebug     = "d"
+	clFormat    = "f"
 	clGenConfig = "g"
 	clHelp      = "h"
 	clOutput    = "o"
 	clVersion   = "v"
 
+	// name of the commands
+	cmdCheck = "check"
+	cmdInit  = "init"
+	cmdWatch = "watch"
+
 	// default values
 	defaultOutputFile = "" // if empty use StdOut
 )
 
 // Args struct is used to manage the command line parameters.
 type Args struct {
+	command   string
 	baseDir   string
+	checkDiff bool
 	config    string
 	debug     bool
+	format    string
 	genConfig bool
 	help      bool
 	output    string
@@ -42,9 +54,11 @@ func NewArgs(appName string, errorHandling flag.ErrorHandling) *Args {
 	a := Args{fs: fs, appName: appName}
 
 	fs.StringVar(&a.config, clConfig, defaultConfigFile(appName), "Configuration file used to generate the package.")
-	fs.StringVar(&a.output, clOutput, defaultOutputFile, "Optional output file for package/config file. If empty stdout will be used.")
+	fs.StringVar(&a.output, clOutput, defaultOutputFile, "Optional output file for package/config file. If empty stdout will be used.\nIf it is a directory (an existing one, or a path ending with /), the package\nis split in the pages_gen.go, loader_gen.go and execute_gen.go files.")
 	fs.BoolVar(&a.debug, clDebug, false, "Debug mode. Overwrite configuration setting:\ndo not cache templates, do not use asset manager and do not format generated code.")
 	fs.BoolVar(&a.help, clHelp, false, "Show command usage information.")
+	fs.StringVar(&a.format, clFormat, "", "Format of the configuration file: \"toml\" or \"json\".\nIf empty, it is based on the file extension: \".json\" for JSON, TOML otherwise.")
+	fs.BoolVar(&a.checkDiff, clCheckDiff, false, "Do not write the package: compare it with the existing output file (-o), ignoring\nthe creation time, and print the differences as a unified diff.\nExit with status 3 if the output file is not up to date.")
 	fs.BoolVar(&a.genConfig, clGenConfig, false, "Generate the configuration file instead of the package.")
 	fs.StringVar(&a.baseDir, clBaseDir, "", "Base directory of the templates files.\nIf present, overwrites the \"template_base_dir\" config parameter.")
 	fs.BoolVar(&a.version, clVersion, false, "Show version informations.")
@@ -60,9 +74,30 @@ func NewArgs(appName string, errorHandling flag.ErrorHandling) *Args {
 }
 
 // Parse parses flag definitions from the argument list, which should not
-// include the command name.
+// include the application name.
+// The first argument can be the name of a command (ex: "check", "init",
+// "watch").
 func (a *Args) Parse(arguments []string) error {
-	return a.fs.Parse(arguments)
+	if len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-") {
+		a.command = arguments[0]
+		arguments = arguments[1:]
+		switch a.command {
+		case cmdCheck, cmdInit, cmdWatch:
+			// ok
+		default:
+			return fmt.Errorf("unknown command %q\nTry '%s -h' for more information.", a.command, a.appName)
+		}
+	}
+	if err := a.fs.Parse(arguments); err != nil {
+		return err
+	}
+	if a.fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q\nTry '%s -h' for more information.", a.fs.Arg(0), a.appName)
+	}
+	if a.checkDiff && a.command != "" {
+		return fmt.Errorf("the -%s option cannot be used with the %s command", clCheckDiff, a.command)
+	}
+	return nil
 }
 
 // isFlagPassed checks if flag was provided.
@@ -78,6 +113,21 @@ func (a *Args) isFlagPassed(name string) bool {
 
 // public methods of the Args struct.
 
+// Check returns true if the check command was given.
+func (a *Args) Check() bool { return a.command == cmdCheck }
+
+// Init returns true if the init command was given.
+// It is not related to the "init" config parameter.
+func (a *Args) Init() bool { return a.command == cmdInit }
+
+// Watch returns true if the watch command was given.
+func (a *Args) Watch() bool { return a.command == cmdWatch }
+
+// CheckDiff returns true if the check flag was setted: the generated package
+// must be compared with the output file instead of written.
+// It is not related to the check command.
+func (a *Args) CheckDiff() bool { return a.checkDiff }
+
 // Debug returns true if debug flag was setted.
 func (a *Args) Debug() bool { return a.debug }
 
@@ -94,6 +144,10 @@ func (a *Args) Version() bool { return a.version }
 // If the config flag was not specified, the default <appname>.conf is used.
 func (a *Args) Config() string { return a.config }
 
+// Format returns the format of the configuration file passed in the command
+// line. If empty, the format is based on the file extension.
+func (a *Args) Format() string { return a.format }
+
 // OutputFile returns the path of the output file.
 func (a *Args) OutputFile() string { return a.output }
 
@@ -0,0 +1,106 @@
+package cmdline
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestArgs_ParseCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments []string
+		check     bool
+		init      bool
+		watch     bool
+		checkDiff bool
+		output    string
+		wantErr   bool
+	}{
+		{
+			name:      "no command",
+			arguments: []string{"-o", "templates.go"},
+			output:    "templates.go",
+		},
+		{
+			name:      "watch",
+			arguments: []string{"watch", "-o", "templates.go"},
+			watch:     true,
+			output:    "templates.go",
+		},
+		{
+			name:      "check",
+			arguments: []string{"check", "-c", "app.conf"},
+			check:     true,
+		},
+		{
+			name:      "init",
+			arguments: []string{"init", "-b", "tmpl", "-o", "app.conf"},
+			init:      true,
+			output:    "app.conf",
+		},
+		{
+			name:      "check flag",
+			arguments: []string{"-check", "-o", "templates.go"},
+			checkDiff: true,
+			output:    "templates.go",
+		},
+		{
+			name:      "check flag with command",
+			arguments: []string{"watch", "-check", "-o", "templates.go"},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown command",
+			arguments: []string{"run", "-o", "templates.go"},
+			wantErr:   true,
+		},
+		{
+			name:      "unexpected argument",
+			arguments: []string{"-o", "templates.go", "watch"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := NewArgs("app", flag.ContinueOnError)
+			args.fs.SetOutput(io.Discard)
+			err := args.Parse(tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if args.Check() != tt.check {
+				t.Errorf("Check() = %v, want %v", args.Check(), tt.check)
+			}
+			if args.Init() != tt.init {
+				t.Errorf("Init() = %v, want %v", args.Init(), tt.init)
+			}
+			if args.Watch() != tt.watch {
+				t.Errorf("Watch() = %v, want %v", args.Watch(), tt.watch)
+			}
+			if args.CheckDiff() != tt.checkDiff {
+				t.Errorf("CheckDiff() = %v, want %v", args.CheckDiff(), tt.checkDiff)
+			}
+			if args.OutputFile() != tt.output {
+				t.Errorf("OutputFile() = %q, want %q", args.OutputFile(), tt.output)
+			}
+		})
+	}
+}
+
+func TestArgs_Format(t *testing.T) {
+	args := NewArgs("app", flag.ContinueOnError)
+	args.fs.SetOutput(io.Discard)
+	if err := args.Parse([]string{"-f", "json", "-c", "app.cfg"}); err != nil {
+		t.Fatal(err)
+	}
+	if args.Format() != "json" {
+		t.Errorf("Format() = %q, want %q", args.Format(), "json")
+	}
+	if args.Config() != "app.cfg" {
+		t.Errorf("Config() = %q, want %q", args.Config(), "app.cfg")
+	}
+}
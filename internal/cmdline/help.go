@@ -3,6 +3,7 @@ package cmdline
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 // PrintHelp prints usage information about the application.
@@ -10,7 +11,7 @@ func (a *Args) PrintHelp(w io.Writer) {
 
 	a.fs.SetOutput(w)
 
-	fmt.Fprintf(w, `Usage: %[1]s [OPTION]...
+	fmt.Fprintf(w, `Usage: %[1]s [COMMAND] [OPTION]...
 
 %[1]s is an utility that generates a go package for parse and render html or
 text templates.
@@ -25,6 +26,21 @@ Moreover for each page of name Name %[1]s defines a constant PageName so that
 to render the page all you have to do is:
   err := PageName.Execute(w, data)
 
+Commands:
+
+  check  Check the configuration file and report all the problems found,
+         without generating the package. Exit with status 1 if any error
+         is found.
+
+  init   Write a configuration file proposing the templates and the pages
+         found scanning the templates files in the base directory (-b).
+         Each file is parsed to find the {{define}} blocks and the
+         {{template}} calls: a page is proposed for each top-level block,
+         with a template containing the files needed by the block.
+
+  watch  Generate the package, and generate it again whenever the
+         configuration file or the templates files change.
+
 Options:
 
 `, a.appName)
@@ -32,12 +48,37 @@ Options:
 	a.fs.PrintDefaults()
 
 	fmt.Fprintf(w, `
+Environment:
+
+  %[3]s_<PARAM>  Overwrite the <PARAM> config parameter, unless it is
+                   overwritten by a command line option
+                   (ex: %[3]s_ASSET_MANAGER=embed).
+
 Examples:
 
   Generate the templates package
     %[1]s -c %[2]s -o templates.go
 
+  Generate the templates package on every change of the configuration or
+  of the templates files
+    %[1]s watch -c %[2]s -o templates.go
+
+  Check the configuration file
+    %[1]s check -c %[2]s
+
+  Check that the templates package is up to date (ex: in CI)
+    %[1]s -check -c %[2]s -o templates.go
+
+  Create the configuration file from the templates files in the tmpl folder
+    %[1]s init -b tmpl -o %[2]s
+
   Generate a demo configuration file
     %[1]s -g -o %[2]s
-`, a.appName, defaultConfigFile(a.appName))
+
+  Generate a demo configuration file in JSON format
+    %[1]s -g -o %[1]s.json
+
+  Generate the templates package without cache
+    %[3]s_NO_CACHE=true %[1]s -c %[2]s -o templates.go
+`, a.appName, defaultConfigFile(a.appName), strings.ToUpper(a.appName))
 }
@@ -2,58 +2,108 @@
 package config
 
 import (
-	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/mmbros/gentmpl/internal/cmdline"
 	"github.com/mmbros/gentmpl/run"
 	"github.com/mmbros/gentmpl/run/types"
-	"github.com/pelletier/go-toml/v2"
 )
 
 type Config struct {
 	run.Context
 	OutputFile string
+
+	// Configuration files to include, relative to the including file.
+	// Their templates, pages and template kinds are merged in the Config.
+	Include []string `toml:"include" json:"include"`
+
+	// Configuration files included, directly or indirectly, by the
+	// configuration file.
+	IncludedFiles []string `toml:"-" json:"-"`
+
+	// Warnings found loading the configuration files (ex: deprecated keys).
+	Warnings []run.Diagnostic `toml:"-" json:"-"`
+
+	// templates defined in the included files
+	included map[string]includedTemplate
 }
 
-// Unmarshal creates a new Config from an array of bytes.
+// Unmarshal creates a new Config from an array of bytes in TOML format.
 func Unmarshal(data []byte) (*Config, error) {
-	// parse config file
-	var cfg Config
-	if err := toml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
+	return UnmarshalFormat(data, FormatTOML)
+}
+
+// UnmarshalFormat creates a new Config from an array of bytes in the given
+// format.
+// It returns an error if a key is not a parameter of the configuration or an
+// attribute of a page. The deprecated keys are accepted, and reported in the
+// Warnings of the Config.
+func UnmarshalFormat(data []byte, format Format) (*Config, error) {
+	return decodeConfig("", data, format)
 }
 
-// FromFile creates a new Config loading the specified configuration file.
+// FromFile creates a new Config loading the specified configuration file,
+// and the files included by it.
+// The format of each file is based on its extension (see FormatOf).
+// The relative paths of the templates files are resolved at generation time
+// against the folder of the configuration file, that is the Dir of the
+// Config. The files paths of the templates defined in an included file are
+// relative to the folder of the included file.
 func FromFile(path string) (*Config, error) {
-	// open config file
-	f, err := os.Open(path)
+	return FromFileFormat(path, "")
+}
+
+// FromFileFormat creates a new Config as FromFile, but the main
+// configuration file is decoded in the given format, if not empty.
+func FromFileFormat(path string, format Format) (*Config, error) {
+	l := &loader{
+		format:    format,
+		templates: map[string]source{},
+		pages:     map[string]string{},
+		kinds:     map[string]string{},
+		positions: map[string]run.Position{},
+	}
+	cfg, err := l.load(path, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	// read config file
-	buf, err := io.ReadAll(f)
-	if err != nil {
+	cfg.Dir = filepath.Dir(path)
+	cfg.included = l.includedTemplates(cfg, path)
+	if err := cfg.rebase(); err != nil {
 		return nil, err
 	}
-	return Unmarshal(buf)
+	cfg.IncludedFiles = l.included
+	cfg.Positions = l.positions
+	return cfg, nil
 }
 
 // Parse create a new Config from
 //  1. command line parameters
-//  2. configuration file
-//  3. default values
+//  2. environment variables (ex: GENTMPL_ASSET_MANAGER=embed)
+//  3. configuration file
+//  4. default values
+//
+// If the templates files are embedded in the package written to the output
+// file, their relative paths are resolved against the folder of the package,
+// as go:embed does, instead of the folder of the configuration file.
 func Parse(args *cmdline.Args) (*Config, error) {
 
 	// init config from the config file
-	cfg, err := FromFile(args.Config())
+	format, err := ParseFormat(args.Format())
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := FromFileFormat(args.Config(), format)
 	if err != nil {
 		return nil, err
 	}
 
+	// update config settings with environment variables
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
 	// update config settings with command line parameters and set defaults
 	if args.IsPassedOutputFile() {
 		cfg.OutputFile = args.OutputFile()
@@ -74,5 +124,38 @@ func Parse(args *cmdline.Args) (*Config, error) {
 		cfg.NoCache = false
 	}
 
+	if cfg.OutputFile != "" {
+		cfg.PackageDir = PackageDir(cfg.OutputFile)
+		// the embedded files are relative to the folder of the package
+		if cfg.AssetManager == types.AssetManagerEmbed {
+			cfg.Dir = cfg.PackageDir
+		}
+	}
+	if err := cfg.rebase(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+// IsOutputDir returns true if the output path is a directory: an existing
+// one, or a path ending with the path separator.
+func IsOutputDir(path string) bool {
+	if path == "" {
+		return false
+	}
+	if os.IsPathSeparator(path[len(path)-1]) {
+		return true
+	}
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// PackageDir returns the folder of the package generated in the output
+// path: the output path itself if it is a directory, or its folder.
+func PackageDir(output string) string {
+	if IsOutputDir(output) {
+		return output
+	}
+	return filepath.Dir(output)
+}
@@ -1,9 +1,18 @@
 package config
 
 import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mmbros/gentmpl/internal/cmdline"
+	"github.com/mmbros/gentmpl/run"
+	"github.com/mmbros/gentmpl/run/types"
 )
 
 const (
@@ -20,6 +29,7 @@ Pag2 = {template="flat", base="page-2"}
 Pag3 = {template="flat", base="page-3"}
 Inh1 = {template="inh1"}
 Inh2 = {template="inh2"}
+User = {template="flat", base="user", data="*models.User", import="example.com/app/models"}
 `
 )
 
@@ -33,15 +43,13 @@ func Test_Unmarshal(t *testing.T) {
 		"inh2":    {"inhbase", "inheritance/content2.tmpl"},
 		"inhbase": {"inheritance/base.tmpl"},
 	}
-	cfg.Pages = map[string]struct {
-		Template string
-		Base     string
-	}{
+	cfg.Pages = map[string]run.Page{
 		"Inh1": {Template: "inh1"},
 		"Inh2": {Template: "inh2"},
 		"Pag1": {Template: "flat", Base: "page-1"},
 		"Pag2": {Template: "flat", Base: "page-2"},
 		"Pag3": {Template: "flat", Base: "page-3"},
+		"User": {Template: "flat", Base: "user", Data: "*models.User", Import: "example.com/app/models"},
 	}
 
 	type args struct {
@@ -73,7 +81,7 @@ func Test_Unmarshal(t *testing.T) {
 				return
 			}
 
-			if diff := cmp.Diff(tt.want, got); diff != "" {
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(run.Context{}, "Positions"), cmpopts.IgnoreUnexported(Config{})); diff != "" {
 				t.Errorf("ToSlice() mismatch (-want +got):\n%s", diff)
 			}
 		})
@@ -100,3 +108,660 @@ func Test_Unmarshal(t *testing.T) {
 // 		})
 // 	}
 // }
+
+// writeFiles writes the files in the dir folder.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// trimDir removes the dir folder from the paths of the files in s.
+func trimDir(dir, s string) string {
+	return strings.ReplaceAll(s, dir+string(filepath.Separator), "")
+}
+
+func TestIsOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "templates.go")
+	if err := os.WriteFile(file, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"", false},
+		{dir, true},
+		{file, false},
+		{filepath.Join(dir, "missing"), false},
+		{filepath.Join(dir, "missing") + string(filepath.Separator), true},
+	}
+	for _, tt := range tests {
+		if got := IsOutputDir(tt.path); got != tt.want {
+			t.Errorf("IsOutputDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{dir, dir},
+		{filepath.Join(dir, "templates.go"), dir},
+		{filepath.Join(dir, "out") + string(filepath.Separator), filepath.Join(dir, "out") + string(filepath.Separator)},
+		{"templates.go", "."},
+	}
+	for _, tt := range tests {
+		if got := PackageDir(tt.output); got != tt.want {
+			t.Errorf("PackageDir(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestFromFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/gentmpl.conf": `template_base_dir = "tmpl"
+include = ["../shared/layouts.conf"]
+[templates]
+home = ["layout", "home.tmpl"]
+[pages]
+Home = {template="home", base="page"}
+`,
+		"shared/layouts.conf": `template_base_dir = "layouts"
+include = ["partials/partials.conf"]
+[templates]
+layout = ["partials", "base.tmpl", "./other/extra.tmpl"]
+[template_kinds]
+layout = "html"
+`,
+		"shared/partials/partials.conf": `[templates]
+partials = ["header.tmpl", "footer/*.tmpl"]
+[pages]
+Header = {template="partials", base="header"}
+`,
+	})
+	cfg, err := FromFile(filepath.Join(dir, "app", "gentmpl.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTemplates := map[string][]string{
+		"home":     {"layout", "home.tmpl"},
+		"layout":   {"partials", "../shared/layouts/base.tmpl", "../shared/other/extra.tmpl"},
+		"partials": {"../shared/partials/header.tmpl", "../shared/partials/footer/*.tmpl"},
+	}
+	if diff := cmp.Diff(wantTemplates, cfg.Templates); diff != "" {
+		t.Errorf("templates mismatch (-want +got):\n%s", diff)
+	}
+	wantPages := map[string]run.Page{
+		"Home":   {Template: "home", Base: "page"},
+		"Header": {Template: "partials", Base: "header"},
+	}
+	if diff := cmp.Diff(wantPages, cfg.Pages); diff != "" {
+		t.Errorf("pages mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"layout": "html"}, cfg.TemplateKinds); diff != "" {
+		t.Errorf("template kinds mismatch (-want +got):\n%s", diff)
+	}
+	wantIncluded := []string{filepath.Join(dir, "shared/layouts.conf"), filepath.Join(dir, "shared/partials/partials.conf")}
+	if diff := cmp.Diff(wantIncluded, cfg.IncludedFiles); diff != "" {
+		t.Errorf("included files mismatch (-want +got):\n%s", diff)
+	}
+	if cfg.TemplateBaseDir != "tmpl" {
+		t.Errorf("template_base_dir: got %q, want %q", cfg.TemplateBaseDir, "tmpl")
+	}
+}
+
+func TestParseIncludeBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gentmpl.conf": `asset_manager = "embed"
+template_base_dir = "tmpl"
+include = ["tmpl/blog/blog.conf"]
+[pages]
+Post = {template="post"}
+`,
+		"tmpl/blog/blog.conf": `[templates]
+post = ["post.tmpl", "../layout.tmpl"]
+`,
+	})
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "file",
+			want: []string{"blog/post.tmpl", "layout.tmpl"},
+		},
+		{
+			name: "command line base dir",
+			args: []string{"-b", "tmpl/blog"},
+			want: []string{"post.tmpl", "./tmpl/layout.tmpl"},
+		},
+		{
+			name: "embed in a sub folder",
+			args: []string{"-o", filepath.Join(dir, "tmpl", "blog", "templates.go"), "-b", "."},
+			want: []string{"post.tmpl", "../layout.tmpl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+			if err := args.Parse(append([]string{"-c", filepath.Join(dir, "gentmpl.conf")}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Parse(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, cfg.Templates["post"]); diff != "" {
+				t.Errorf("post mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromFileIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		errLike string
+	}{
+		{
+			name: "template conflict",
+			files: map[string]string{
+				"gentmpl.conf": "include = [\"a.conf\"]\n[templates]\nflat = [\"flat.tmpl\"]\n",
+				"a.conf":       "[templates]\nflat = [\"other.tmpl\"]\n",
+			},
+			errLike: `template "flat" defined in both gentmpl.conf and a.conf`,
+		},
+		{
+			name: "page conflict",
+			files: map[string]string{
+				"gentmpl.conf": "include = [\"a.conf\", \"b.conf\"]\n",
+				"a.conf":       "[pages]\nHome = {template=\"flat\"}\n",
+				"b.conf":       "[pages]\nHome = {template=\"flat\"}\n",
+			},
+			errLike: `page "Home" defined in both a.conf and b.conf`,
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"gentmpl.conf": "include = [\"a.conf\"]\n",
+				"a.conf":       "include = [\"sub/b.conf\"]\n",
+				"sub/b.conf":   "include = [\"../a.conf\"]\n",
+			},
+			errLike: "include cycle:",
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"gentmpl.conf": "include = [\"a.conf\"]\n",
+			},
+			errLike: "a.conf",
+		},
+		{
+			name: "parameter not allowed",
+			files: map[string]string{
+				"gentmpl.conf": "include = [\"a.conf\"]\n",
+				"a.conf":       "no_cache = true\n",
+			},
+			errLike: `a.conf:1:1: parameter "no_cache" cannot be set in an included file`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			_, err := FromFile(filepath.Join(dir, "gentmpl.conf"))
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !strings.Contains(trimDir(dir, err.Error()), tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want Format
+	}{
+		{"gentmpl.conf", FormatTOML},
+		{"gentmpl.toml", FormatTOML},
+		{"gentmpl.json", FormatJSON},
+		{"GENTMPL.JSON", FormatJSON},
+		{"", FormatTOML},
+	}
+	for _, tt := range tests {
+		if got := FormatOf(tt.path); got != tt.want {
+			t.Errorf("FormatOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFromFileJSON(t *testing.T) {
+	const txtJSON = `{
+  "template_base_dir": "tmpl/",
+  "asset_manager": "embed",
+  "templates": {
+    "flat": ["flat/footer.tmpl", "flat/header.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl"],
+    "inhbase": ["inheritance/base.tmpl"],
+    "inh1": ["inhbase", "inheritance/content1.tmpl"],
+    "inh2": ["inhbase", "inheritance/content2.tmpl"]
+  },
+  "pages": {
+    "Pag1": {"template": "flat", "base": "page-1"},
+    "Pag2": {"template": "flat", "base": "page-2"},
+    "Pag3": {"template": "flat", "base": "page-3"},
+    "Inh1": {"template": "inh1"},
+    "Inh2": {"template": "inh2"},
+    "User": {"template": "flat", "base": "user", "data": "*models.User", "import": "example.com/app/models"}
+  }
+}
+`
+	want, err := Unmarshal([]byte("asset_manager = \"embed\"\n" + txtConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gentmpl.json": txtJSON,
+		"json.conf":    txtJSON,
+	})
+	want.Dir = dir
+
+	// format based on the file extension
+	got, err := FromFile(filepath.Join(dir, "gentmpl.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignorePositions := cmp.Options{cmpopts.IgnoreFields(run.Context{}, "Positions"), cmpopts.IgnoreUnexported(Config{})}
+	if diff := cmp.Diff(want, got, ignorePositions); diff != "" {
+		t.Errorf("gentmpl.json mismatch (-want +got):\n%s", diff)
+	}
+
+	// explicit format
+	if _, err := FromFile(filepath.Join(dir, "json.conf")); err == nil {
+		t.Errorf("json.conf: expected TOML parse error")
+	}
+	got, err = FromFileFormat(filepath.Join(dir, "json.conf"), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got, ignorePositions); diff != "" {
+		t.Errorf("json.conf mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteConfigJSON(t *testing.T) {
+	cfg, err := Unmarshal([]byte(txtConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Init = types.InitModeLazy
+	cfg.TemplateKinds = map[string]string{"inh1": "text"}
+
+	var buf bytes.Buffer
+	if err := cfg.Context.WriteConfigJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalFormat(buf.Bytes(), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(cfg, got, cmpopts.IgnoreFields(run.Context{}, "Positions"), cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("WriteConfigJSON mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"GENTMPL_ASSET_MANAGER":     "embed",
+		"GENTMPL_NO_CACHE":          "true",
+		"GENTMPL_TEMPLATE_BASE_DIR": "views",
+		"GENTMPL_INIT":              "lazy",
+		"GENTMPL_FUNC_MAP":          "",
+		"GENTMPL_TEMPLATES":         "ignored",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	cfg := &Config{}
+	cfg.FuncMap = "funcMap"
+	if err := applyEnv(cfg, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AssetManager != types.AssetManagerEmbed {
+		t.Errorf("asset_manager: got %q, want %q", cfg.AssetManager, types.AssetManagerEmbed)
+	}
+	if !cfg.NoCache {
+		t.Errorf("no_cache: got false, want true")
+	}
+	if cfg.TemplateBaseDir != "views" {
+		t.Errorf("template_base_dir: got %q, want %q", cfg.TemplateBaseDir, "views")
+	}
+	if !cfg.Init.IsLazy() {
+		t.Errorf("init: got %q, want %q", cfg.Init, types.InitModeLazy)
+	}
+	if cfg.FuncMap != "funcMap" {
+		t.Errorf("func_map: got %q, want %q", cfg.FuncMap, "funcMap")
+	}
+	wantEnv := []string{
+		"GENTMPL_NO_CACHE",
+		"GENTMPL_ASSET_MANAGER",
+		"GENTMPL_INIT",
+		"GENTMPL_TEMPLATE_BASE_DIR",
+	}
+	if diff := cmp.Diff(wantEnv, cfg.Env); diff != "" {
+		t.Errorf("env mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	tests := []struct {
+		key, value string
+		errLike    string
+	}{
+		{"GENTMPL_NO_CACHE", "maybe", `environment variable GENTMPL_NO_CACHE: invalid value "maybe"`},
+		{"GENTMPL_ASSET_MANAGER", "zip", `environment variable GENTMPL_ASSET_MANAGER: invalid value "zip"`},
+		{"GENTMPL_INIT", "later", `environment variable GENTMPL_INIT: invalid value "later"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				if key == tt.key {
+					return tt.value, true
+				}
+				return "", false
+			}
+			err := applyEnv(&Config{}, lookup)
+			if err == nil || !strings.Contains(err.Error(), tt.errLike) {
+				t.Errorf("applyEnv: got error %v, want error like %q", err, tt.errLike)
+			}
+		})
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gentmpl.conf": `package_name = "templates"
+template_base_dir = "tmpl"
+no_go_format = true
+[templates]
+home = ["home.tmpl"]
+[pages]
+Home = {template="home"}
+`,
+	})
+	t.Setenv("GENTMPL_TEMPLATE_BASE_DIR", "views")
+	t.Setenv("GENTMPL_NO_GO_FORMAT", "false")
+	t.Setenv("GENTMPL_PACKAGE_NAME", "pages")
+
+	args := cmdline.NewArgs("gentmpl", flag.ContinueOnError)
+	if err := args.Parse([]string{"-c", filepath.Join(dir, "gentmpl.conf"), "-b", "layouts"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// command line > environment > configuration file
+	if cfg.TemplateBaseDir != "layouts" {
+		t.Errorf("template_base_dir: got %q, want %q", cfg.TemplateBaseDir, "layouts")
+	}
+	if cfg.NoGoFormat {
+		t.Errorf("no_go_format: got true, want false")
+	}
+	if cfg.PackageName != "pages" {
+		t.Errorf("package_name: got %q, want %q", cfg.PackageName, "pages")
+	}
+}
+
+func TestFromFilePositions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gentmpl.conf": `no_cache = true
+include = ["shared.json"]
+
+[templates]
+flat = ["flat/page.tmpl"]
+
+[pages]
+Pag1 = {template="flat", base="page-1"}
+  "Pag2" = {template="flat2"}
+
+[pages.Pag3]
+template = "flat"
+`,
+		"shared.json": `{
+  "templates": {
+    "shared": ["shared.tmpl"]
+  },
+  "pages": {"Shared": {"template": "shared"}}
+}
+`,
+	})
+	cfg, err := FromFile(filepath.Join(dir, "gentmpl.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"no_cache":              "gentmpl.conf:1:1",
+		"templates.flat":        "gentmpl.conf:5:1",
+		"pages.Pag1":            "gentmpl.conf:8:1",
+		"pages.Pag1.base":       "gentmpl.conf:8:26",
+		"pages.Pag2":            "gentmpl.conf:9:3",
+		"pages.Pag3":            "gentmpl.conf:11:8",
+		"pages.Pag3.template":   "gentmpl.conf:12:1",
+		"templates.shared":      "shared.json:3:5",
+		"pages.Shared":          "shared.json:5:13",
+		"pages.Shared.template": "shared.json:5:24",
+	}
+	for key, pos := range want {
+		if got := trimDir(dir, cfg.Positions[key].String()); got != pos {
+			t.Errorf("position of %s: got %q, want %q", key, got, pos)
+		}
+	}
+	// only the definitions of the included files are indexed
+	if pos := cfg.Positions["templates"]; pos.File != filepath.Join(dir, "gentmpl.conf") {
+		t.Errorf("position of templates: got %q, want the main file", pos)
+	}
+
+	cfg.NoTemplateCheck = true
+	wantErr := `gentmpl.conf:9:3: page Pag2: template "flat2" not defined`
+	if err := cfg.Check(); err == nil || trimDir(dir, err.Error()) != wantErr {
+		t.Errorf("Check() error = %v, want %q", err, wantErr)
+	}
+}
+
+func TestFromFileDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		errLike string
+	}{
+		{
+			name:    "toml",
+			file:    "gentmpl.conf",
+			content: "no_cache = true\n[pages]\nPag1 = {template=}\n",
+			errLike: "gentmpl.conf:3:18: toml: ",
+		},
+		{
+			name:    "toml type",
+			file:    "gentmpl.conf",
+			content: "no_cache = true\npackage_name = 1\n",
+			errLike: "gentmpl.conf:2:16: toml: ",
+		},
+		{
+			name:    "json",
+			file:    "gentmpl.json",
+			content: "{\n  \"no_cache\": true,\n  \"pages\": }\n",
+			errLike: "gentmpl.json:3:12: invalid character",
+		},
+		{
+			name:    "json type",
+			file:    "gentmpl.json",
+			content: "{\n  \"no_cache\": 1\n}\n",
+			errLike: "gentmpl.json:2:15: json: cannot unmarshal number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{tt.file: tt.content})
+
+			_, err := FromFile(filepath.Join(dir, tt.file))
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !strings.Contains(trimDir(dir, err.Error()), tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+		})
+	}
+}
+
+func TestFromFileUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr []string
+	}{
+		{
+			name:    "toml",
+			file:    "gentmpl.conf",
+			content: "no_cahce = true\nfoo = 1\n[pages]\nPag1 = {template=\"flat\", bsae=\"page-1\"}\n",
+			wantErr: []string{
+				`gentmpl.conf:1:1: unknown key "no_cahce" (did you mean "no_cache"?)`,
+				`gentmpl.conf:2:1: unknown key "foo"`,
+				`gentmpl.conf:4:26: unknown key "pages.Pag1.bsae" (did you mean "base"?)`,
+			},
+		},
+		{
+			name:    "json",
+			file:    "gentmpl.json",
+			content: "{\n  \"no_cahce\": true,\n  \"pages\": {\"Pag1\": {\"template\": \"flat\", \"bsae\": \"page-1\"}}\n}\n",
+			wantErr: []string{
+				`gentmpl.json:2:3: unknown key "no_cahce" (did you mean "no_cache"?)`,
+				`gentmpl.json:3:42: unknown key "pages.Pag1.bsae" (did you mean "base"?)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{tt.file: tt.content})
+
+			_, err := FromFile(filepath.Join(dir, tt.file))
+			if err == nil {
+				t.Fatalf("expected errors %q; no error found", tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantErr, strings.Split(trimDir(dir, err.Error()), "\n")); diff != "" {
+				t.Errorf("errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromFileLegacyKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "toml",
+			file:    "gentmpl.conf",
+			content: "text_remplate = true\nTemplateEnumType = \"tmpl\"\n",
+			want: []string{
+				`gentmpl.conf:2:1: warning: key "TemplateEnumType" is deprecated: use "template_enum_type"`,
+				`gentmpl.conf:1:1: warning: key "text_remplate" is deprecated: use "text_template"`,
+			},
+		},
+		{
+			name:    "json",
+			file:    "gentmpl.json",
+			content: "{\n  \"text_remplate\": true,\n  \"TemplateEnumType\": \"tmpl\"\n}\n",
+			want: []string{
+				`gentmpl.json:3:3: warning: key "TemplateEnumType" is deprecated: use "template_enum_type"`,
+				`gentmpl.json:2:3: warning: key "text_remplate" is deprecated: use "text_template"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{tt.file: tt.content})
+
+			cfg, err := FromFile(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cfg.TextTemplate || cfg.TemplateEnumType != "tmpl" {
+				t.Errorf("legacy keys not applied: text_template=%v, template_enum_type=%q", cfg.TextTemplate, cfg.TemplateEnumType)
+			}
+			var got []string
+			for _, w := range cfg.Warnings {
+				got = append(got, trimDir(dir, w.String()))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromFileLegacyKeyConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gentmpl.conf": "text_template = true\ntext_remplate = false\n",
+	})
+	want := `gentmpl.conf:2:1: deprecated key "text_remplate" cannot be used together with "text_template"`
+	if _, err := FromFile(filepath.Join(dir, "gentmpl.conf")); err == nil || trimDir(dir, err.Error()) != want {
+		t.Errorf("FromFile() error = %v, want %q", err, want)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	known := []string{"no_cache", "no_go_format", "package_name", "text_template"}
+	tests := []struct {
+		key, want string
+	}{
+		{"no_cahce", "no_cache"},
+		{"NoCache", "no_cache"},
+		{"PACKAGE_NAME", "package_name"},
+		{"text_templat", "text_template"},
+		{"foo", ""},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.key, known); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
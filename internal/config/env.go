@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run"
+)
+
+// envPrefix is the prefix of the environment variables that override the
+// configuration parameters.
+const envPrefix = "GENTMPL_"
+
+// envParam is a configuration parameter that can be overridden by an
+// environment variable.
+type envParam struct {
+	name  string // parameter name (ex: "asset_manager")
+	index int    // index of the field in run.Context
+}
+
+// envParams returns the parameters of run.Context that can be overridden by
+// an environment variable: all the parameters, except the maps (templates,
+// pages and template kinds).
+func envParams() []envParam {
+	var params []envParam
+	t := reflect.TypeOf(run.Context{})
+	for j := 0; j < t.NumField(); j++ {
+		f := t.Field(j)
+		name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if name == "" || name == "-" || f.Type.Kind() == reflect.Map {
+			continue
+		}
+		params = append(params, envParam{name: name, index: j})
+	}
+	return params
+}
+
+// EnvVar returns the name of the environment variable that overrides the
+// given configuration parameter (ex: "asset_manager" -> "GENTMPL_ASSET_MANAGER").
+func EnvVar(param string) string {
+	return envPrefix + strings.ToUpper(param)
+}
+
+// applyEnv overrides the parameters of cfg with the values of the
+// environment variables returned by lookup. The variables with an empty
+// value are ignored.
+// The names of the applied variables are appended to cfg.Env: their values
+// (ex: absolute paths) are not recorded, so that the generated package does
+// not depend on the machine.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	ctx := reflect.ValueOf(&cfg.Context).Elem()
+	for _, p := range envParams() {
+		key := EnvVar(p.name)
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setValue(ctx.Field(p.index), value); err != nil {
+			return fmt.Errorf("environment variable %s: invalid value %q: %s", key, value, err.Error())
+		}
+		cfg.Env = append(cfg.Env, key)
+	}
+	return nil
+}
+
+// setValue sets the field to the value parsed from s.
+func setValue(field reflect.Value, s string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.String:
+		field.SetString(s)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Format is the format of a configuration file.
+type Format string
+
+// Format possible values
+const (
+	FormatTOML Format = "toml"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat converts a string to a Format value.
+// An empty string is converted to an empty Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "", FormatTOML, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid configuration format: %q", s)
+}
+
+// FormatOf returns the format of the configuration file based on its
+// extension: ".json" files use the JSON format, all the others (ex: ".toml",
+// ".conf") the TOML format.
+func FormatOf(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return FormatJSON
+	}
+	return FormatTOML
+}
+
+// decode decodes the data in the given format into v.
+func decode(data []byte, format Format, v any) error {
+	if format == FormatJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		return dec.Decode(v)
+	}
+	return toml.Unmarshal(data, v)
+}
+
+// decodeStrict decodes the data in the given format into v, as decode.
+// The keys of a TOML document that do not match any field of v are reported
+// with a *toml.StrictMissingError, after decoding all the other keys.
+func decodeStrict(data []byte, format Format, v any) error {
+	if format == FormatJSON {
+		return decode(data, format, v)
+	}
+	dec := toml.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// encode encodes v in the given format.
+func encode(v any, format Format) ([]byte, error) {
+	if format == FormatJSON {
+		return json.Marshal(v)
+	}
+	return toml.Marshal(v)
+}
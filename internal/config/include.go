@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run"
+)
+
+// includeParams are the parameters that can be set in an included file.
+var includeParams = []string{"include", "pages", "template_base_dir", "template_kinds", "templates"}
+
+// source is the configuration file that defines a template.
+type source struct {
+	path    string // path of the configuration file
+	dir     string // absolute path of the folder of the configuration file
+	baseDir string // template_base_dir of the configuration file
+}
+
+// loader loads a configuration file and the files included by it.
+type loader struct {
+	// format of the main configuration file: if empty, it is based on the
+	// file extension
+	format Format
+
+	// stack of the absolute paths of the files being loaded,
+	// used to detect the include cycles
+	stack []string
+	// all the loaded files, except the main one
+	included []string
+
+	// mapping from template, page or template kind name to the file that
+	// defines it
+	templates map[string]source
+	pages     map[string]string
+	kinds     map[string]string
+
+	// positions of the keys of all the loaded files
+	positions map[string]run.Position
+}
+
+// load loads the configuration file, and merges in cfg the templates, pages
+// and template kinds of the file and of the files included by it.
+// If cfg is nil, the file is the main configuration file and all its
+// parameters are loaded.
+func (l *loader) load(path string, cfg *Config) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for j, p := range l.stack {
+		if p == abs {
+			cycle := append(l.stack[j:], abs)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := FormatOf(path)
+	if cfg == nil && l.format != "" {
+		format = l.format
+	}
+	cur, err := decodeConfig(path, buf, format)
+	if err != nil {
+		return nil, err
+	}
+	positions := cur.Positions
+
+	if cfg == nil {
+		// main configuration file: the maps are filled by merge
+		main := *cur
+		main.Templates, main.Pages, main.TemplateKinds = nil, nil, nil
+		cfg = &main
+	} else {
+		if err := checkIncludeParams(path, buf, format, positions); err != nil {
+			return nil, err
+		}
+		l.included = append(l.included, path)
+		cfg.Warnings = append(cfg.Warnings, cur.Warnings...)
+	}
+
+	src := source{path: path, dir: filepath.Dir(abs), baseDir: cur.TemplateBaseDir}
+	if err := l.merge(cfg, cur, src, positions); err != nil {
+		return nil, err
+	}
+
+	for _, inc := range cur.Include {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		if _, err := l.load(inc, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// merge adds to cfg the templates, pages and template kinds of cur, defined
+// in the src file, and the positions of their keys. All the positions are
+// added if cfg is the main configuration file.
+// It returns an error if any of them is already defined.
+func (l *loader) merge(cfg, cur *Config, src source, positions map[string]run.Position) error {
+	for key, pos := range positions {
+		if _, ok := l.positions[key]; ok {
+			continue
+		}
+		if len(l.stack) == 1 || isDefinitionKey(key) {
+			l.positions[key] = pos
+		}
+	}
+
+	for _, name := range sortedKeys(cur.Templates) {
+		if other, ok := l.templates[name]; ok {
+			return fmt.Errorf("%s: template %q defined in both %s and %s", positions["templates."+name], name, other.path, src.path)
+		}
+		if cfg.Templates == nil {
+			cfg.Templates = map[string][]string{}
+		}
+		cfg.Templates[name] = cur.Templates[name]
+		l.templates[name] = src
+	}
+	for _, name := range sortedKeys(cur.Pages) {
+		if other, ok := l.pages[name]; ok {
+			return fmt.Errorf("%s: page %q defined in both %s and %s", positions["pages."+name], name, other, src.path)
+		}
+		if cfg.Pages == nil {
+			cfg.Pages = map[string]run.Page{}
+		}
+		cfg.Pages[name] = cur.Pages[name]
+		l.pages[name] = src.path
+	}
+	for _, name := range sortedKeys(cur.TemplateKinds) {
+		if other, ok := l.kinds[name]; ok {
+			return fmt.Errorf("%s: template kind %q defined in both %s and %s", positions["template_kinds."+name], name, other, src.path)
+		}
+		if cfg.TemplateKinds == nil {
+			cfg.TemplateKinds = map[string]string{}
+		}
+		cfg.TemplateKinds[name] = cur.TemplateKinds[name]
+		l.kinds[name] = src.path
+	}
+	return nil
+}
+
+// includedTemplate is a template defined in an included file.
+type includedTemplate struct {
+	src   source   // file that defines the template
+	items []string // items of the template, as written in the file
+}
+
+// includedTemplates returns the templates of cfg defined in the included
+// files, with their items as written in the files.
+func (l *loader) includedTemplates(cfg *Config, main string) map[string]includedTemplate {
+	included := map[string]includedTemplate{}
+	for name, src := range l.templates {
+		if src.path == main {
+			continue
+		}
+		included[name] = includedTemplate{src: src, items: cfg.Templates[name]}
+	}
+	return included
+}
+
+// rebase rewrites the files paths of the templates defined in the included
+// files, that are relative to the folder of the included file (joined with
+// its template_base_dir), so that they are resolved as the items of the
+// main configuration file. The paths inside the template_base_dir folder
+// are made relative to it; the other ones are made relative to the Dir
+// folder and start with "." or "/", so that they are not joined with the
+// template_base_dir.
+// The paths are computed from the items as written in the included files,
+// so that rebase can be called again when Dir or TemplateBaseDir change.
+func (cfg *Config) rebase() error {
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	base := cfg.TemplateBaseDir
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(dir, base)
+	}
+	for name, inc := range cfg.included {
+		rebased := make([]string, len(inc.items))
+		for j, item := range inc.items {
+			if _, isTemplate := cfg.Templates[item]; isTemplate || filepath.IsAbs(item) {
+				rebased[j] = item
+				continue
+			}
+			path := item
+			if !strings.HasPrefix(item, ".") {
+				path = filepath.Join(inc.src.baseDir, item)
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(inc.src.dir, path)
+			}
+			if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, ".") {
+				rebased[j] = filepath.ToSlash(rel)
+				continue
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return fmt.Errorf("%s: template %s: %w", inc.src.path, name, err)
+			}
+			if !strings.HasPrefix(rel, ".") {
+				rel = "." + string(filepath.Separator) + rel
+			}
+			rebased[j] = filepath.ToSlash(rel)
+		}
+		cfg.Templates[name] = rebased
+	}
+	return nil
+}
+
+// isDefinitionKey returns true if the key is, or is inside, the definition
+// of a template, page or template kind (ex: "pages.Pag1").
+func isDefinitionKey(key string) bool {
+	for _, prefix := range []string{"templates.", "pages.", "template_kinds."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIncludeParams returns an error if the included file sets parameters
+// other than the includeParams.
+func checkIncludeParams(path string, data []byte, format Format, positions map[string]run.Position) error {
+	var params map[string]any
+	if err := decode(data, format, &params); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range sortedKeys(params) {
+		if !contains(includeParams, key) {
+			return fmt.Errorf("%s: parameter %q cannot be set in an included file", positions[key], key)
+		}
+	}
+	return nil
+}
+
+func contains(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// keyPositions returns the positions of the keys of the configuration file,
+// indexed by their dotted path (ex: "pages.Pag2", "templates.flat").
+// If a key is defined more than once, the first position is returned.
+// The data must be already decoded without errors.
+func keyPositions(path string, data []byte, format Format) map[string]run.Position {
+	if format == FormatJSON {
+		return jsonKeyPositions(path, data)
+	}
+	return tomlKeyPositions(path, data)
+}
+
+// tomlKeyPositions returns the positions of the keys of a TOML document.
+func tomlKeyPositions(path string, data []byte) map[string]run.Position {
+	positions := map[string]run.Position{}
+
+	var p unstable.Parser
+	p.Reset(data)
+
+	// add adds the positions of the parts of a dotted key, and returns the
+	// full path of the key
+	add := func(prefix []string, it unstable.Iterator) []string {
+		keys := append([]string(nil), prefix...)
+		for it.Next() {
+			node := it.Node()
+			keys = append(keys, string(node.Data))
+			key := strings.Join(keys, ".")
+			if _, ok := positions[key]; !ok {
+				start := p.Shape(node.Raw).Start
+				positions[key] = run.Position{File: path, Line: start.Line, Column: start.Column}
+			}
+		}
+		return keys
+	}
+
+	// keyValue adds the positions of the key, and of the keys of its value
+	// if it is an inline table
+	var keyValue func(prefix []string, kv *unstable.Node)
+	keyValue = func(prefix []string, kv *unstable.Node) {
+		keys := add(prefix, kv.Key())
+		if value := kv.Value(); value.Kind == unstable.InlineTable {
+			it := value.Children()
+			for it.Next() {
+				keyValue(keys, it.Node())
+			}
+		}
+	}
+
+	var table []string
+	for p.NextExpression() {
+		expr := p.Expression()
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = add(nil, expr.Key())
+		case unstable.KeyValue:
+			keyValue(table, expr)
+		}
+	}
+	return positions
+}
+
+// jsonKeyPositions returns the positions of the keys of a JSON document.
+func jsonKeyPositions(path string, data []byte) map[string]run.Position {
+	positions := map[string]run.Position{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// value reads a value, adding the positions of its keys if it is an
+	// object
+	var value func(prefix string) error
+	value = func(prefix string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				// the key starts at the first quote after the previous token
+				offset := int(dec.InputOffset())
+				offset += bytes.IndexByte(data[offset:], '"')
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := tok.(string)
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				if _, ok := positions[key]; !ok {
+					positions[key] = offsetPosition(path, data, offset)
+				}
+				if err := value(key); err != nil {
+					return err
+				}
+			}
+		case json.Delim('['):
+			for dec.More() {
+				if err := value(prefix); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+		// closing delimiter
+		_, err = dec.Token()
+		return err
+	}
+	// the data is already decoded, so no error is expected
+	_ = value("")
+	return positions
+}
+
+// offsetPosition returns the position of the byte at the given offset.
+func offsetPosition(path string, data []byte, offset int) run.Position {
+	offset = max(0, min(offset, len(data)))
+	lead := data[:offset]
+	return run.Position{
+		File:   path,
+		Line:   bytes.Count(lead, []byte{'\n'}) + 1,
+		Column: len(lead) - bytes.LastIndexByte(lead, '\n'),
+	}
+}
+
+// decodeError returns the error err, returned decoding the data of the
+// configuration file, prefixed by its position in the file.
+func decodeError(path string, data []byte, err error) error {
+	var (
+		tomlErr   *toml.DecodeError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	pos := run.Position{File: path}
+	switch {
+	case errors.As(err, &tomlErr):
+		pos.Line, pos.Column = tomlErr.Position()
+	case errors.As(err, &syntaxErr):
+		// the offset is after the invalid character
+		pos = offsetPosition(path, data, int(syntaxErr.Offset)-1)
+	case errors.As(err, &typeErr):
+		// the offset is after the invalid value
+		pos = offsetPosition(path, data, int(typeErr.Offset)-1)
+	}
+	if pos.String() == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", pos, err)
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// legacyKeys maps the deprecated keys of the configuration file, that are
+// still accepted with a warning, to the keys that replace them.
+var legacyKeys = map[string]string{
+	// misspelled in the README up to v2.0
+	"text_remplate": "text_template",
+	// the parameter had no tag up to v2.0, and so was matched by field name
+	"TemplateEnumType": "template_enum_type",
+}
+
+// decodeConfig creates a new Config from the data of the configuration file
+// in the given format. The path of the file, if not empty, is used in the
+// positions of the keys and of the errors.
+// The keys that are not parameters of the configuration, or attributes of a
+// page, are reported as errors, with a suggestion of the most similar key.
+// The legacy keys are replaced by the keys that replace them, and reported
+// as warnings.
+func decodeConfig(path string, data []byte, format Format) (*Config, error) {
+	var cfg Config
+	err := decodeStrict(data, format, &cfg)
+	var missing *toml.StrictMissingError
+	if err != nil && !errors.As(err, &missing) {
+		return nil, decodeError(path, data, err)
+	}
+	cfg.Positions = keyPositions(path, data, format)
+
+	// unknown keys
+	var errs []error
+	if missing != nil {
+		for _, e := range missing.Errors {
+			key := []string(e.Key())
+			if len(key) == 1 && legacyKeys[key[0]] != "" {
+				continue
+			}
+			line, column := e.Position()
+			errs = append(errs, unknownKeyError(run.Position{File: path, Line: line, Column: column}, key))
+		}
+	}
+	if format == FormatJSON {
+		// encoding/json does not report the position of the unknown keys:
+		// they are found among the keys of the document
+		keys := make([]string, 0, len(cfg.Positions))
+		for key := range cfg.Positions {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			pi, pj := cfg.Positions[keys[i]], cfg.Positions[keys[j]]
+			return pi.Line < pj.Line || pi.Line == pj.Line && pi.Column < pj.Column
+		})
+		for _, key := range keys {
+			parts := strings.Split(key, ".")
+			if len(parts) == 1 && legacyKeys[key] != "" {
+				continue
+			}
+			if known := knownKeys(parts); known != nil && !containsFold(known, parts[len(parts)-1]) {
+				errs = append(errs, unknownKeyError(cfg.Positions[key], parts))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := cfg.replaceLegacyKeys(data, format); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// replaceLegacyKeys sets the parameters given with a legacy key, adding a
+// warning for each of them.
+func (cfg *Config) replaceLegacyKeys(data []byte, format Format) error {
+	var params map[string]any
+	if err := decode(data, format, &params); err != nil {
+		return err
+	}
+	for _, legacy := range sortedKeys(legacyKeys) {
+		value, ok := params[legacy]
+		if !ok {
+			continue
+		}
+		key := legacyKeys[legacy]
+		pos := cfg.Positions[legacy]
+		if _, ok := params[key]; ok {
+			return fmt.Errorf("%s: deprecated key %q cannot be used together with %q", pos, legacy, key)
+		}
+		// decode the value as if it was given with the new key
+		buf, err := encode(map[string]any{key: value}, format)
+		if err == nil {
+			err = decode(buf, format, cfg)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", pos, legacy, err)
+		}
+		cfg.Warnings = append(cfg.Warnings, run.Diagnostic{
+			Severity: run.SeverityWarning,
+			Pos:      pos,
+			Message:  fmt.Sprintf("key %q is deprecated: use %q", legacy, key),
+		})
+	}
+	return nil
+}
+
+// unknownKeyError returns the error of an unknown key, with the path of the
+// key and the suggestion of the most similar known key, if any.
+func unknownKeyError(pos run.Position, path []string) error {
+	name := path[len(path)-1]
+	msg := fmt.Sprintf("unknown key %q", strings.Join(path, "."))
+	if s := suggest(name, knownKeys(path)); s != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	return &run.ConfigError{Pos: pos, Msg: msg}
+}
+
+// knownKeys returns the keys that can be used at the path of the given key:
+// the parameters of the configuration for a top-level key, and the
+// attributes of a page for a key of a page.
+// It returns nil for the other keys.
+func knownKeys(path []string) []string {
+	switch {
+	case len(path) == 1:
+		return structKeys(reflect.TypeOf(Config{}))
+	case len(path) == 3 && path[0] == "pages":
+		return structKeys(reflect.TypeOf(run.Page{}))
+	}
+	return nil
+}
+
+// structKeys returns the names of the toml tags of the fields of the
+// struct type t, including the fields of the embedded structs.
+func structKeys(t reflect.Type) []string {
+	var keys []string
+	for j := 0; j < t.NumField(); j++ {
+		f := t.Field(j)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			keys = append(keys, structKeys(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// suggest returns the known key most similar to key, or an empty string if
+// no known key is similar enough.
+func suggest(key string, known []string) string {
+	best, bestDist := "", len(key)/3+1
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(key), k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to change a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func containsFold(a []string, s string) bool {
+	for _, x := range a {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
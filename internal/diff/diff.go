@@ -0,0 +1,199 @@
+// Package diff implements the unified diff of two texts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// number of unchanged lines around the changes
+	contextLines = 3
+
+	// maximum number of edits searched by the Myers algorithm: if the texts
+	// differ more, every old line is replaced by every new line
+	maxEdits = 1000
+)
+
+// op is a line of the edit script.
+type op struct {
+	kind byte // ' ' (unchanged), '-' (deleted) or '+' (inserted)
+	line string
+}
+
+// Unified returns the unified diff, with 3 lines of context, that changes
+// the old text into the new one. The names of the texts are used in the
+// "---" and "+++" lines.
+// It returns an empty string if the texts are equal.
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := edits(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	// line numbers, starting at 0, of the current op in the old and new text
+	var oldLine, newLine int
+	for i := 0; i < len(ops); {
+		// skip the unchanged lines before the next change
+		j := i
+		for j < len(ops) && ops[j].kind == ' ' {
+			j++
+		}
+		if j == len(ops) {
+			break
+		}
+		start := max(i, j-contextLines)
+		oldLine += start - i
+		newLine += start - i
+
+		// the hunk ends when more than 2*contextLines unchanged lines
+		// follow a change
+		end := j
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			k := end
+			for k < len(ops) && ops[k].kind == ' ' {
+				k++
+			}
+			if k == len(ops) || k-end > 2*contextLines {
+				end = min(k, end+contextLines)
+				break
+			}
+			end = k
+		}
+
+		hunk := ops[start:end]
+		var oldCount, newCount int
+		for _, o := range hunk {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, o := range hunk {
+			b.WriteByte(o.kind)
+			b.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange returns the range of a hunk starting after line lines, as
+// "start,count", or "start" if count is 1.
+// The start of an empty range is the line before the hunk.
+func hunkRange(line, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", line)
+	case 1:
+		return fmt.Sprintf("%d", line+1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// splitLines returns the lines of the text, each with its trailing newline
+// if any.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits returns the shortest edit script that changes a into b, found with
+// the Myers algorithm.
+func edits(a, b []string) []op {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] contains v[offset-d:offset+d+1] before the d-th step
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= n+m && d <= maxEdits && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		ops := make([]op, 0, n+m)
+		for _, line := range a {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{'+', line})
+		}
+		return ops
+	}
+
+	// backtrack from the end of the texts
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		at := func(k int) int { return vd[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		var prevX int
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{'+', b[prevY]})
+			} else {
+				ops = append(ops, op{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
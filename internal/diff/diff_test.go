@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines from 1 to n, as "1\n2\n...".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for j := range lines {
+		lines[j] = fmt.Sprint(j + 1)
+	}
+	return lines
+}
+
+func text(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestUnified(t *testing.T) {
+	changed := numbered(20)
+	changed[1] = "two"
+	changed[14] = "fifteen"
+	changed = append(changed[:17], changed[18:]...)
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "hunks",
+			old:  text(numbered(20)),
+			new:  text(changed),
+			want: `--- old
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -12,9 +12,8 @@
+ 12
+ 13
+ 14
+-15
++fifteen
+ 16
+ 17
+-18
+ 19
+ 20
+`,
+		},
+		{
+			name: "from empty",
+			old:  "",
+			new:  "a\nb\n",
+			want: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			name: "to empty",
+			old:  "a\n",
+			new:  "",
+			want: `--- old
++++ new
+@@ -1 +0,0 @@
+-a
+`,
+		},
+		{
+			name: "no newline at end",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.old, tt.new); got != tt.want {
+				t.Errorf("Unified():\ngot\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditsMinimal(t *testing.T) {
+	a := splitLines("a\nb\nc\na\nb\nb\na\n")
+	b := splitLines("c\nb\na\nb\na\nc\n")
+	var changes int
+	for _, o := range edits(a, b) {
+		if o.kind != ' ' {
+			changes++
+		}
+	}
+	// the shortest edit script of the Myers paper example
+	if changes != 5 {
+		t.Errorf("edits(): %d changes, want 5", changes)
+	}
+}
+
+func TestEditsTooMany(t *testing.T) {
+	var a, b []string
+	for j := 0; j < maxEdits; j++ {
+		a = append(a, fmt.Sprintf("a%d\n", j))
+		b = append(b, fmt.Sprintf("b%d\n", j))
+	}
+	ops := edits(a, b)
+	if len(ops) != 2*maxEdits || ops[0].kind != '-' || ops[len(ops)-1].kind != '+' {
+		t.Errorf("edits(): expected every line replaced, found %d ops", len(ops))
+	}
+}
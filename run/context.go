@@ -4,16 +4,18 @@ package run
 import (
 	"bytes"
 	_ "embed"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"go/format"
 	"io"
+	"mime"
+	"os"
 	"path/filepath"
+	"strconv"
 	"text/template"
 	"time"
 
 	"github.com/mmbros/gentmpl/run/collection"
-	"github.com/mmbros/gentmpl/run/lib"
 	"github.com/mmbros/gentmpl/run/types"
 )
 
@@ -28,6 +30,7 @@ const (
 	defaultPageEnumType     = "PageEnum"
 	defaultPagePrefix       = "Page"
 	defaultTemplateEnumType = "templateEnum"
+	defaultGoldenDir        = "testdata"
 )
 
 // Context contains the parameters that manage the code generation.
@@ -37,60 +40,152 @@ const (
 type Context struct {
 	// Do not cache the templates.
 	// A new template will be created on every page.Execute.
-	NoCache bool `toml:"no_cache"`
+	NoCache bool `toml:"no_cache" json:"no_cache"`
 
 	// Do not format the generated code with go/format.
-	NoGoFormat bool `toml:"no_go_format"`
+	NoGoFormat bool `toml:"no_go_format" json:"no_go_format"`
+
+	// Do not write the creation time in the header of the generated code,
+	// so that the output depends only on the configuration and the
+	// templates. If false, the time is taken from the SOURCE_DATE_EPOCH
+	// environment variable, if set, or is the current time.
+	NoTimestamp bool `toml:"no_timestamp" json:"no_timestamp"`
 
 	// Package name used in the generated code.
-	PackageName string `toml:"package_name"`
+	PackageName string `toml:"package_name" json:"package_name"`
 
 	// Asset manager to use. Possible values:
 	// - none (default)
 	// - go-bindata
-	AssetManager types.AssetManager `toml:"asset_manager"`
+	AssetManager types.AssetManager `toml:"asset_manager" json:"asset_manager"`
 
 	// Use text/template instead of html/template.
-	TextTemplate bool `toml:"text_template"`
+	TextTemplate bool `toml:"text_template" json:"text_template"`
+
+	// Mapping from template name to the kind of the template, that overrides
+	// TextTemplate for the template. Possible values:
+	// - html: use html/template
+	// - text: use text/template
+	// The kind of the template used by a page determines the engine used to
+	// render the page.
+	TemplateKinds map[string]string `toml:"template_kinds" json:"template_kinds,omitempty"`
+
+	// Templates initialization mode. Possible values:
+	// - eager (default): all the templates are created by LoadTemplates
+	// - lazy: each template is created on first use
+	// Ignored if NoCache is true.
+	Init types.InitMode `toml:"init" json:"init"`
+
+	// Generate the Render and RenderString methods of the PageEnum type,
+	// that render the page into a buffer and write the output only if no
+	// error occurs.
+	BufferedRender bool `toml:"buffered_render" json:"buffered_render"`
+
+	// Execute renders the page into a buffer, as Render does.
+	// Implies BufferedRender.
+	BufferedExecute bool `toml:"buffered_execute" json:"buffered_execute"`
 
 	// Name of the template.FuncMap variable used in template creation.
 	// The variable must be defined in another file of the same package
 	// (ex: "templates/func-map.go").
 	// If empty, no funcMap will be used.
-	FuncMap string `toml:"func_map"`
+	FuncMap string `toml:"func_map" json:"func_map"`
 
 	// Name of the PageEnum type definition.
-	PageEnumType string `toml:"page_enum_type"`
+	PageEnumType string `toml:"page_enum_type" json:"page_enum_type"`
 
 	// Strings used as prefix and suffix in the PageEnum constants.
 	// Example:  page "CreateUser", prefix="Page", suffix="" -> PageCreateUser
-	PageEnumPrefix string `toml:"page_enum_prefix"`
-	PageEnumSuffix string `toml:"page_enum_suffix"`
+	PageEnumPrefix string `toml:"page_enum_prefix" json:"page_enum_prefix"`
+	PageEnumSuffix string `toml:"page_enum_suffix" json:"page_enum_suffix"`
 
 	// Name of the TemplateEnum type definition.
-	TemplateEnumType string
+	TemplateEnumType string `toml:"template_enum_type" json:"template_enum_type"`
 
 	// Base folder of the templates files.
-	TemplateBaseDir string `toml:"template_base_dir"`
+	TemplateBaseDir string `toml:"template_base_dir" json:"template_base_dir"`
+
+	// Do not read and parse the templates files at generation time.
+	// Useful if the files are not available when the package is generated.
+	NoTemplateCheck bool `toml:"no_template_check" json:"no_template_check"`
+
+	// Generate the golden test file of the package (see WriteGoldenTest),
+	// that renders each page with the data of the <GoldenDir>/<Page>.json
+	// fixture and compares the output with the <GoldenDir>/<Page>.golden
+	// file.
+	GoldenTests bool `toml:"golden_tests" json:"golden_tests"`
+
+	// Folder of the fixture and golden files of the golden tests, relative
+	// to the folder of the generated package. (default "testdata")
+	GoldenDir string `toml:"golden_dir" json:"golden_dir"`
+
+	// Directory used to resolve the relative paths of the templates files
+	// at generation time (ex: the folder of the configuration file). If
+	// empty, the current directory is used.
+	Dir string `toml:"-" json:"-"`
+
+	// Folder of the generated package, whose source files are parsed to
+	// check that the FuncMap variable does not define the "context"
+	// function. If empty, the FuncMap variable is not checked.
+	PackageDir string `toml:"-" json:"-"`
+
+	// Names of the environment variables that override the configuration
+	// parameters (ex: "GENTMPL_NO_CACHE"), reported in the header of the
+	// generated package.
+	Env []string `toml:"-" json:"-"`
+
+	// Positions of the keys in the configuration files (ex: "pages.Pag2",
+	// "templates.flat"), used to report the position of the errors.
+	Positions map[string]Position `toml:"-" json:"-"`
 
 	// Mapping from template name to items used to create the template.
 	// Each item can be a:
 	// - file path to parse in the template creation.
+	// - glob pattern of the files to parse (ex: "partials/**/*.tmpl").
 	// - name of another template to include in the current template.
-	Templates map[string][]string `toml:"templates"`
+	Templates map[string][]string `toml:"templates" json:"templates"`
 
-	// Mapping from page name to template name and base values used to render
-	// the page.
-	Pages map[string]struct {
-		Template string
-		Base     string
-	}
+	// Mapping from page name to the parameters used to render the page.
+	Pages map[string]Page `toml:"pages" json:"pages"`
+}
+
+// Page contains the parameters used to render a page.
+type Page struct {
+	// Name of the template used to render the page.
+	Template string `toml:"template" json:"template"`
+
+	// Name of the template to execute.
+	// If empty, template.Execute will be used instead of template.ExecuteTemplate.
+	Base string `toml:"base" json:"base,omitempty"`
+
+	// Optional Go type of the data passed to the page (ex: "*models.UserPage").
+	// If defined, a type-safe Render<Page> function is generated.
+	Data string `toml:"data" json:"data,omitempty"`
+
+	// Import path of the package referenced by the Data type, if any
+	// (ex: "example.com/app/models").
+	Import string `toml:"import" json:"import,omitempty"`
+
+	// Optional media type of the page output, used as value of the
+	// Content-Type header (ex: "application/atom+xml; charset=utf-8").
+	// If empty, "text/html; charset=utf-8" is used, or
+	// "text/plain; charset=utf-8" if TextTemplate is true.
+	ContentType string `toml:"content_type" json:"content_type,omitempty"`
+
+	// Optional http.ServeMux pattern of the page (ex: "GET /users/{id}").
+	// If defined, the page is registered by the generated Register func.
+	Route string `toml:"route" json:"route,omitempty"`
+
+	// HTTP method of the route, if not given in the route itself
+	// (ex: "GET").
+	Method string `toml:"method" json:"method,omitempty"`
 }
 
 // dataType contains all the information passed to the template used to
 // generate the package in WritePackage.
 type dataType struct {
 	ProgramName      string
+	Env              []string
 	Timestamp        time.Time
 	NoCache          bool
 	NoGoFormat       bool
@@ -100,20 +195,49 @@ type dataType struct {
 	TemplateBaseDir  string
 	TemplateEnumType string
 	PageEnumType     string
-	TextTemplate     bool
+	TextTemplate     bool // all the templates use text/template
+	Mixed            bool // templates use both html/template and text/template
+	BufferedRender   bool
+	BufferedExecute  bool
+	Lazy             bool
+	GoldenDir        string // folder of the fixture and golden files
 
 	Pages     []string // page names (sorted)
 	Bases     []string // base names
 	Templates []string // used template names (sorted)
 	Files     []string // used files
 	PI2BI     []int    // page-index to base-index
+	PI2CT     []string // page-index to content type
 	PI2TI     []int    // page-index to template-index
 	TI2AFI    [][]int  // template-index to array of file-index
+	TI2Text   []bool   // template-index to true if text/template is used
+
+	TypedPages []typedPage  // pages with a data type (sorted by name)
+	Imports    []importSpec // imports needed by the data types (sorted by path)
+	Routes     []pageRoute  // pages with a route (sorted by name)
 
 	pageEnumPrefix string
 	pageEnumSuffix string
 }
 
+// timestamp returns the creation time written in the header of the
+// generated code: the zero time if NoTimestamp is true, the time given by
+// the SOURCE_DATE_EPOCH environment variable (in UTC) if set, or the current
+// time.
+func (ctx *Context) timestamp() (time.Time, error) {
+	if ctx.NoTimestamp {
+		return time.Time{}, nil
+	}
+	if s := os.Getenv("SOURCE_DATE_EPOCH"); s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: must be a number of seconds", s)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return time.Now(), nil
+}
+
 func nvl(a, b string) string {
 	if a == "" {
 		return b
@@ -122,22 +246,17 @@ func nvl(a, b string) string {
 }
 
 // checkAndPrepare check for errors in the Context's parameters.
+// The parameters are checked by Validate, and the first error found is
+// returned.
 // If no error is found, returns the dataTaype object created based on the Context
 func (ctx *Context) checkAndPrepare() (*dataType, error) {
-	var err error
-
-	// asset manager
-	switch ctx.AssetManager {
-	case types.AssetManagerNone, types.AssetManagerEmbed:
-		// ok
-	default:
-		return nil, fmt.Errorf("assetManager not supported: %q", ctx.AssetManager)
+	for _, diag := range ctx.Validate() {
+		if diag.Severity == SeverityError {
+			return nil, &ConfigError{Pos: diag.Pos, Msg: diag.Message}
+		}
 	}
 
 	// pages
-	if len(ctx.Pages) == 0 {
-		return nil, errors.New("no pages found")
-	}
 	pages := collection.NewUniqueStrings()
 	for pageName := range ctx.Pages {
 		pages.Add(pageName)
@@ -147,18 +266,22 @@ func (ctx *Context) checkAndPrepare() (*dataType, error) {
 	// templates used by the pages
 	templates := collection.NewUniqueStrings()
 	for _, pageName := range pages.ToSlice() {
-		templateName := ctx.Pages[pageName].Template
-		if templateName == "" {
-			return nil, fmt.Errorf("page must have a template: page=%s", pageName)
-		}
-		_, ok := ctx.Templates[templateName]
-		if !ok {
-			return nil, fmt.Errorf("template not found for page: page=%s, template=%s", pageName, templateName)
-		}
-		templates.Add(templateName)
+		templates.Add(ctx.Pages[pageName].Template)
 	}
 	templates.Sort()
 
+	// data types of the pages
+	typedPages, imports, err := ctx.pageDataTypes(pages.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
+	// routes of the pages
+	routes, err := ctx.pageRoutes(pages.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
 	// page-index -> template-idx
 	// Note: must evaluate after pages.Sort and templates.Sort
 	pi2ti := make([]int, pages.Len())
@@ -168,9 +291,21 @@ func (ctx *Context) checkAndPrepare() (*dataType, error) {
 		pi2ti[pageIdx] = templateIdx
 	}
 
+	// kinds of the templates
+	ti2text, err := ctx.templateKinds(templates.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
+	// content types of the pages
+	pi2ct, err := ctx.pageContentTypes(pages.ToSlice(), pi2ti, ti2text)
+	if err != nil {
+		return nil, err
+	}
+
 	// resolve used templates
 	// mapping from template name -> (file1, file2, ...)
-	t2af, err := lib.ResolveIncludes(ctx.Templates, templates.ToSlice())
+	t2af, err := ctx.resolveTemplates(templates.ToSlice())
 	if err != nil {
 		return nil, err
 	}
@@ -200,9 +335,15 @@ func (ctx *Context) checkAndPrepare() (*dataType, error) {
 		ti2afi[tmplIdx] = fileIdxs
 	}
 
+	timestamp, err := ctx.timestamp()
+	if err != nil {
+		return nil, err
+	}
+
 	data := &dataType{
 		ProgramName:      "gentmpl",
-		Timestamp:        time.Now(),
+		Env:              ctx.Env,
+		Timestamp:        timestamp,
 		NoCache:          ctx.NoCache,
 		NoGoFormat:       ctx.NoGoFormat,
 		AssetManager:     ctx.AssetManager,
@@ -211,7 +352,12 @@ func (ctx *Context) checkAndPrepare() (*dataType, error) {
 		PageEnumType:     nvl(ctx.PageEnumType, defaultPageEnumType),
 		FuncMap:          ctx.FuncMap,
 		TemplateBaseDir:  ctx.TemplateBaseDir,
-		TextTemplate:     ctx.TextTemplate,
+		TextTemplate:     !isMixed(ti2text) && ti2text[0],
+		Mixed:            isMixed(ti2text),
+		BufferedRender:   ctx.BufferedRender || ctx.BufferedExecute,
+		BufferedExecute:  ctx.BufferedExecute,
+		Lazy:             ctx.Init.IsLazy() && !ctx.NoCache,
+		GoldenDir:        nvl(ctx.GoldenDir, defaultGoldenDir),
 
 		Pages:     pages.ToSlice(),
 		Templates: templates.ToSlice(),
@@ -219,7 +365,13 @@ func (ctx *Context) checkAndPrepare() (*dataType, error) {
 		Files:     files.ToSlice(),
 		PI2TI:     pi2ti,
 		PI2BI:     pi2bi,
+		PI2CT:     pi2ct,
 		TI2AFI:    ti2afi,
+		TI2Text:   ti2text,
+
+		TypedPages: typedPages,
+		Imports:    imports,
+		Routes:     routes,
 
 		pageEnumPrefix: nvl(ctx.PageEnumPrefix, defaultPagePrefix),
 		pageEnumSuffix: ctx.PageEnumSuffix,
@@ -233,6 +385,50 @@ func (d *dataType) PageName(name string) string {
 	return d.pageEnumPrefix + name + d.pageEnumSuffix
 }
 
+// TmplType returns the type of the templates in the generated code.
+func (d *dataType) TmplType() string {
+	if d.Mixed {
+		return "Executor"
+	}
+	return "*template.Template"
+}
+
+// engineData is passed to the templates that generate the code of a
+// template engine (html/template or text/template).
+type engineData struct {
+	*dataType
+	Pkg string // package name of the template engine in the generated code
+}
+
+// Engine returns the data used to generate the code of the template engine
+// imported with the given package name.
+func (d *dataType) Engine(pkg string) *engineData {
+	return &engineData{dataType: d, Pkg: pkg}
+}
+
+// RenderName returns the name of the type-safe render function of the page
+// with given name.
+func (d *dataType) RenderName(name string) string {
+	return renderName(name)
+}
+
+// renderName returns the name of the type-safe render function of the page
+// with given name.
+func renderName(name string) string {
+	return "Render" + name
+}
+
+// PageData returns the Go type of the data of the page with given name, or
+// "any" if the page has no data type.
+func (d *dataType) PageData(name string) string {
+	for _, p := range d.TypedPages {
+		if p.Name == name {
+			return p.Data
+		}
+	}
+	return "any"
+}
+
 // getTemplate init the template used to write the package
 func getTemplate() *template.Template {
 	// define the functions available in the template
@@ -257,40 +453,46 @@ func getTemplate() *template.Template {
 // WritePackage prints the generated package to writer.
 func (ctx *Context) WritePackage(w io.Writer) error {
 
-	var (
-		buf bytes.Buffer // A Buffer needs no initialization.
-		p   []byte
-	)
-
 	// check and prepare context
 	data, err := ctx.checkAndPrepare()
 	if err != nil {
 		return err
 	}
 
-	// execute the named template
-	t := getTemplate()
-	err = t.ExecuteTemplate(&buf, "package", data)
+	p, err := ctx.generate(getTemplate(), "package", data)
 	if err != nil {
 		return err
 	}
 
-	if ctx.NoGoFormat {
-		p = buf.Bytes()
-	} else {
-		// execute format source
-		p, err = format.Source(buf.Bytes())
-		if err != nil {
-			return fmt.Errorf("formatting source: %s", err.Error())
-		}
-	}
-
 	// write bytes to writer
 	_, err = w.Write(p)
 
 	return err
 }
 
+// generate executes the named template with data, and returns the
+// generated code without the unused imports, formatted with go/format
+// unless NoGoFormat is true.
+func (ctx *Context) generate(t *template.Template, name string, data *dataType) ([]byte, error) {
+	var buf bytes.Buffer // A Buffer needs no initialization.
+
+	// execute the named template
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	p := pruneImports(buf.Bytes())
+
+	if ctx.NoGoFormat {
+		return p, nil
+	}
+	// execute format source
+	p, err := format.Source(p)
+	if err != nil {
+		return nil, fmt.Errorf("formatting source: %s", err.Error())
+	}
+	return p, nil
+}
+
 // WriteConfig prints the current Context to writer using a TOML file format.
 // The file has comments describing each parameter of the configuration.
 func (ctx *Context) WriteConfig(w io.Writer) error {
@@ -298,7 +500,51 @@ func (ctx *Context) WriteConfig(w io.Writer) error {
 	return t.ExecuteTemplate(w, "toml", ctx)
 }
 
+// WriteConfigJSON prints the current Context to writer using a JSON file
+// format, with the same parameter names of the TOML file format.
+func (ctx *Context) WriteConfigJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(ctx, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
+
+// pageContentTypes returns the content type of each page.
+// The content types are checked with mime.ParseMediaType.
+// The default content type of a page depends on the kind of its template.
+func (ctx *Context) pageContentTypes(pageNames []string, pi2ti []int, ti2text []bool) ([]string, error) {
+	pi2ct := make([]string, len(pageNames))
+	for pageIdx, pageName := range pageNames {
+		contentType := ctx.Pages[pageName].ContentType
+		if contentType == "" {
+			if ti2text[pi2ti[pageIdx]] {
+				pi2ct[pageIdx] = "text/plain; charset=utf-8"
+			} else {
+				pi2ct[pageIdx] = "text/html; charset=utf-8"
+			}
+			continue
+		}
+		if err := checkContentType(contentType); err != nil {
+			return nil, ctx.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+		}
+		pi2ct[pageIdx] = contentType
+	}
+	return pi2ct, nil
+}
+
+// checkContentType checks that contentType is a valid media type.
+func checkContentType(contentType string) error {
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return fmt.Errorf("invalid content type %q: %s", contentType, err.Error())
+	}
+	return nil
+}
+
 // Check check for errors in the Context's parameters.
+// It returns the first error reported by Validate.
 func (ctx *Context) Check() error {
 	_, err := ctx.checkAndPrepare()
 	return err
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -42,15 +43,21 @@ var templates = map[string][]string{
 	"inh1":    {"inhbase", "inheritance/content1.tmpl"},
 	"inh2":    {"inhbase", "inheritance/content2.tmpl"},
 }
-var pages = map[string]struct {
-	Template string
-	Base     string
-}{
-	"Pag1": {"flat", "page-1"},
-	"Pag2": {"flat", "page-2"},
-	"Pag3": {"flat", "page-3"},
-	"Inh1": {"inh1", ""},
-	"Inh2": {"inh2", ""},
+
+// templatesGlob defines the same templates using glob patterns.
+var templatesGlob = map[string][]string{
+	"flat":    {"flat/*.tmpl"},
+	"inhbase": {"inheritance/base.tmpl"},
+	"inh1":    {"inhbase", "inheritance/content1.tmpl"},
+	"inh2":    {"inhbase", "inheritance/content[2].tmpl"},
+}
+
+var pages = map[string]Page{
+	"Pag1": {Template: "flat", Base: "page-1"},
+	"Pag2": {Template: "flat", Base: "page-2"},
+	"Pag3": {Template: "flat", Base: "page-3"},
+	"Inh1": {Template: "inh1"},
+	"Inh2": {Template: "inh2", Data: "map[string]string"},
 }
 
 // var results = map[string]string{
@@ -112,15 +119,12 @@ func TestCheck(t *testing.T) {
 
 	// test page without a template
 	ctx = &Context{
-		Pages: map[string]struct {
-			Template string
-			Base     string
-		}{
+		Pages: map[string]Page{
 			"Pag": {},
 		},
 	}
 	err = ctx.Check()
-	checkErr(err, "page with no template", "page must have a template")
+	checkErr(err, "page with no template", "page Pag: template not given")
 
 	// test template's page not found
 	tmpl := map[string][]string{}
@@ -129,15 +133,12 @@ func TestCheck(t *testing.T) {
 	}
 	ctx = &Context{Pages: pages, Templates: tmpl}
 	err = ctx.Check()
-	checkErr(err, "no template", "template not found for page")
+	checkErr(err, "no template", `page Inh2: template "inh2" not defined`)
 
 	// test cyclic templates
 	ctx = &Context{
-		Pages: map[string]struct {
-			Template string
-			Base     string
-		}{
-			"Pag": {"t1", ""},
+		Pages: map[string]Page{
+			"Pag": {Template: "t1"},
 		},
 		Templates: map[string][]string{
 			"t1": {"p1", "t2"},
@@ -147,15 +148,65 @@ func TestCheck(t *testing.T) {
 	err = ctx.Check()
 	checkErr(err, "cyclic templates", "found invalid cycle")
 
+	// test invalid content type
+	ctx = &Context{
+		Pages: map[string]Page{
+			"Pag": {Template: "flat", ContentType: "text/html; charset"},
+		},
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	err = ctx.Check()
+	checkErr(err, "invalid content type", "page Pag: invalid content type")
+}
+
+func TestPageContentTypes(t *testing.T) {
+	pgs := map[string]Page{
+		"Feed": {Template: "flat", ContentType: "application/atom+xml; charset=utf-8"},
+		"Home": {Template: "flat"},
+		"Mail": {Template: "inh1"},
+	}
+	const (
+		atom = "application/atom+xml; charset=utf-8"
+		html = "text/html; charset=utf-8"
+		text = "text/plain; charset=utf-8"
+	)
+	tests := []struct {
+		textTemplate bool
+		kinds        map[string]string
+		want         []string
+	}{
+		{false, nil, []string{atom, html, html}},
+		{true, nil, []string{atom, text, text}},
+		{false, map[string]string{"inh1": "text"}, []string{atom, html, text}},
+		{true, map[string]string{"inh1": "html"}, []string{atom, text, html}},
+	}
+	for _, tt := range tests {
+		ctx := &Context{
+			Pages:           pgs,
+			Templates:       templates,
+			TextTemplate:    tt.textTemplate,
+			TemplateKinds:   tt.kinds,
+			NoTemplateCheck: true,
+		}
+		data, err := ctx.checkAndPrepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(data.PI2CT, tt.want) {
+			t.Errorf("text_template=%v, kinds=%v: got %q, want %q", tt.textTemplate, tt.kinds, data.PI2CT, tt.want)
+		}
+	}
 }
 
 func TestWritePackage(t *testing.T) {
 
 	ctx := &Context{
-		Pages:        pages,
-		Templates:    templates,
-		TextTemplate: true,
-		AssetManager: types.AssetManagerNone}
+		Pages:           pages,
+		Templates:       templates,
+		TextTemplate:    true,
+		NoTemplateCheck: true,
+		AssetManager:    types.AssetManagerNone}
 	buf := new(bytes.Buffer)
 	err := ctx.WritePackage(buf)
 	if err != nil {
@@ -290,6 +341,15 @@ func main(){
 
 	if err := page.Execute(wr, nil); err != nil {
 		fmt.Print(err)
+		os.Exit(1)
+	}
+	if err := RenderInh2(wr, map[string]string{}); err != nil {
+		fmt.Print(err)
+		os.Exit(1)
+	}
+	if err := Reload(); err != nil {
+		fmt.Print(err)
+		os.Exit(1)
 	}
 }
 `
@@ -332,27 +392,36 @@ func execGoRun(dir string) error {
 
 func subtestRun(ctx *Context, folder, root string, t *testing.T) {
 
-	var mapFuncs = map[string]func(*Context, string) error{
-		"tmpl":      writeTmplFolder,
-		"templates": writeTemplates,
-		"funcmap":   writeFuncmap,
-		"bindata":   writeBindata,
-		"main":      writeMain,
-		"go.mod":    writeMod,
+	// the templates files must be written before the package is generated
+	var writeFuncs = []struct {
+		title string
+		fn    func(*Context, string) error
+	}{
+		{"tmpl", writeTmplFolder},
+		{"templates", writeTemplates},
+		{"funcmap", writeFuncmap},
+		{"bindata", writeBindata},
+		{"main", writeMain},
+		{"go.mod", writeMod},
 	}
 	var numerr int
 	dir := filepath.Join(root, folder)
 
+	// resolve the templates files relative to the subtest folder
+	c := *ctx
+	c.Dir = dir
+	ctx = &c
+
 	//  create the subtest folder
 	if err := os.MkdirAll(dir, 0777); err != nil {
 		t.Error(err)
 	}
 
 	// create the needed files in the dir
-	for title, fn := range mapFuncs {
-		if err := fn(ctx, dir); err != nil {
+	for _, wf := range writeFuncs {
+		if err := wf.fn(ctx, dir); err != nil {
 			numerr++
-			t.Errorf("%s/%s: %s", folder, title, err.Error())
+			t.Errorf("%s/%s: %s", folder, wf.title, err.Error())
 		}
 	}
 
@@ -510,7 +579,7 @@ func TestRunAll(t *testing.T) {
 	ctx := &Context{
 		PackageName:     "main",
 		Pages:           pages,
-		Templates:       templates,
+		Templates:       templatesGlob,
 		TemplateBaseDir: templateBaseDir,
 	}
 
@@ -539,3 +608,952 @@ func TestRunAll(t *testing.T) {
 		}
 	}
 }
+
+func TestWritePackageTypedPages(t *testing.T) {
+	ctx := &Context{
+		Pages: map[string]Page{
+			"User":  {Template: "flat", Base: "page-1", Data: "*models.User", Import: "example.com/app/models"},
+			"Users": {Template: "flat", Base: "page-2", Data: "[]models.User", Import: "example.com/app/models"},
+			"Map":   {Template: "flat", Base: "page-3", Data: "map[string]any"},
+			"Plain": {Template: "flat", Base: "page-3"},
+		},
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	buf := new(bytes.Buffer)
+	if err := ctx.WritePackage(buf); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, find := range []string{
+		`models "example.com/app/models"`,
+		"func RenderUser(wr io.Writer, data *models.User) error",
+		"func RenderUsers(wr io.Writer, data []models.User) error",
+		"func RenderMap(wr io.Writer, data map[string]any) error",
+	} {
+		if !strings.Contains(buf.String(), find) {
+			t.Errorf("Expected %q not found", find)
+		}
+	}
+	if strings.Contains(buf.String(), "RenderPlain") {
+		t.Errorf("Unexpected RenderPlain found")
+	}
+
+	// the driver package name is used by the generated Value method
+	ctx.Pages["Conn"] = Page{Template: "flat", Base: "page-1", Data: "*driver.Conn", Import: "example.com/driver"}
+	err := ctx.WritePackage(new(bytes.Buffer))
+	if errLike := `page Conn: package name "driver" conflicts with the imports of the generated code`; err == nil || !errorLike(err, errLike) {
+		t.Errorf("WritePackage() error = %v, want error like %q", err, errLike)
+	}
+
+	// the content variable embeds the templates files
+	delete(ctx.Pages, "Conn")
+	ctx.AssetManager = types.AssetManagerEmbed
+	ctx.Pages["Post"] = Page{Template: "flat", Base: "page-1", Data: "*content.Post", Import: "example.com/content"}
+	err = ctx.WritePackage(new(bytes.Buffer))
+	if errLike := `page Post: package name "content" conflicts with an identifier of the generated package`; err == nil || !errorLike(err, errLike) {
+		t.Errorf("WritePackage() error = %v, want error like %q", err, errLike)
+	}
+}
+
+func TestWritePackagePageInfo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestWritePackagePageInfo: skipping test in short mode")
+	}
+
+	const mainGo = `package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+)
+
+func main() {
+	var errs []string
+	check := func(what string, got, want any) {
+		if !reflect.DeepEqual(got, want) {
+			errs = append(errs, fmt.Sprintf("%s = %v, want %v", what, got, want))
+		}
+	}
+
+	var seq []PageEnum
+	for page := range PagesSeq() {
+		seq = append(seq, page)
+	}
+	check("PagesSeq()", seq, AllPages())
+	check("AllPages()", AllPages(), []PageEnum{PageInh1, PageInh2, PagePag1, PagePag2, PagePag3})
+
+	check("String()", PagePag2.String(), "Pag2")
+	check("String() of invalid page", PageEnum(99).String(), "PageEnum(99)")
+	check("IsValid()", PagePag3.IsValid(), true)
+	check("IsValid() of invalid page", PageEnum(99).IsValid(), false)
+
+	page, err := ParsePage("Inh2")
+	check("ParsePage()", page, PageInh2)
+	check("ParsePage() error", err, nil)
+	_, err = ParsePage("Missing")
+	check("ParsePage() of unknown page", fmt.Sprint(err), "unknown page \"Missing\"")
+
+	check("Info()", PagePag2.Info(), PageInfo{
+		Name:        "Pag2",
+		Template:    "flat",
+		Base:        "page-2",
+		Files:       []string{"flat/footer.tmpl", "flat/header.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl"},
+		ContentType: "text/html; charset=utf-8",
+	})
+
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+`
+	dir := t.TempDir()
+	ctx := &Context{
+		PackageName:     "main",
+		Pages:           pages,
+		Templates:       templates,
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+	}
+	for _, fn := range []func(*Context, string) error{writeTmplFolder, writeTemplates, writeMod} {
+		if err := fn(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFile(filepath.Join(dir, "main.go"), mainGo); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGoRun(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWritePackagePageMarshal(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestWritePackagePageMarshal: skipping test in short mode")
+	}
+
+	const mainGo = `package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+var (
+	_ encoding.TextMarshaler   = PageEnum(0)
+	_ encoding.TextUnmarshaler = (*PageEnum)(nil)
+	_ driver.Valuer            = PageEnum(0)
+	_ sql.Scanner              = (*PageEnum)(nil)
+)
+
+type job struct {
+	Page  PageEnum
+	Pages map[PageEnum]int
+}
+
+func main() {
+	var errs []string
+	check := func(what string, got, want any) {
+		if !reflect.DeepEqual(got, want) {
+			errs = append(errs, fmt.Sprintf("%s = %v, want %v", what, got, want))
+		}
+	}
+
+	b, err := json.Marshal(job{Page: PagePag2, Pages: map[PageEnum]int{PageInh1: 1}})
+	check("json.Marshal()", string(b), ` + "`" + `{"Page":"Pag2","Pages":{"Inh1":1}}` + "`" + `)
+	check("json.Marshal() error", err, nil)
+	var j job
+	err = json.Unmarshal(b, &j)
+	check("json.Unmarshal()", j, job{Page: PagePag2, Pages: map[PageEnum]int{PageInh1: 1}})
+	check("json.Unmarshal() error", err, nil)
+
+	err = json.Unmarshal([]byte(` + "`" + `{"Page":"Pag9"}` + "`" + `), &j)
+	var unknown *UnknownPageError
+	check("json.Unmarshal() of unknown page", errors.As(err, &unknown) && unknown.Name == "Pag9", true)
+	_, err = json.Marshal(PageEnum(99))
+	check("json.Marshal() of invalid page fails", err != nil, true)
+
+	v, err := PageInh2.Value()
+	check("Value()", v, driver.Value("Inh2"))
+	check("Value() error", err, nil)
+	var page PageEnum
+	for _, src := range []any{"Pag3", []byte("Pag3")} {
+		page = 0
+		err = page.Scan(src)
+		check(fmt.Sprintf("Scan(%T)", src), page, PagePag3)
+		check(fmt.Sprintf("Scan(%T) error", src), err, nil)
+	}
+	err = page.Scan("Pag9")
+	check("Scan() of unknown page", errors.As(err, &unknown) && unknown.Name == "Pag9", true)
+	check("Scan() of unknown page keeps the page", page, PagePag3)
+	check("Scan(int64) error", fmt.Sprint(page.Scan(int64(1))), "cannot scan int64 into PageEnum")
+
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+`
+	dir := t.TempDir()
+	ctx := &Context{
+		PackageName:     "main",
+		Pages:           pages,
+		Templates:       templates,
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+	}
+	for _, fn := range []func(*Context, string) error{writeTmplFolder, writeTemplates, writeMod} {
+		if err := fn(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFile(filepath.Join(dir, "main.go"), mainGo); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGoRun(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWritePackageEnv(t *testing.T) {
+	ctx := &Context{
+		Pages:           pages,
+		Templates:       templates,
+		NoCache:         true,
+		NoTemplateCheck: true,
+		Env:             []string{"GENTMPL_NO_CACHE", "GENTMPL_TEMPLATE_BASE_DIR"},
+	}
+	buf := new(bytes.Buffer)
+	if err := ctx.WritePackage(buf); err != nil {
+		t.Fatal(err.Error())
+	}
+	find := `func_map="" (env: GENTMPL_NO_CACHE, GENTMPL_TEMPLATE_BASE_DIR)` + "\n"
+	if !strings.Contains(buf.String(), find) {
+		t.Errorf("Expected %q not found", find)
+	}
+}
+
+func TestWritePackageTimestamp(t *testing.T) {
+	tests := []struct {
+		name        string
+		noTimestamp bool
+		epoch       string
+		find        string
+		errLike     string
+	}{
+		{
+			name:  "source date epoch",
+			epoch: "1000000000",
+			find:  "// Created: 2001-09-09 01:46:40\n",
+		},
+		{
+			name:        "no timestamp",
+			noTimestamp: true,
+			epoch:       "1000000000",
+		},
+		{
+			name:    "invalid source date epoch",
+			epoch:   "yesterday",
+			errLike: `invalid SOURCE_DATE_EPOCH "yesterday"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+			ctx := &Context{
+				Pages:           pages,
+				Templates:       templates,
+				NoTemplateCheck: true,
+				NoTimestamp:     tt.noTimestamp,
+			}
+			buf := new(bytes.Buffer)
+			err := ctx.WritePackage(buf)
+			if tt.errLike != "" {
+				if !errorLike(err, tt.errLike) {
+					t.Errorf("WritePackage() error = %v, want error like %q", err, tt.errLike)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := strings.Contains(buf.String(), "// Created: ")
+			if tt.find == "" && created {
+				t.Errorf("Unexpected creation time found")
+			}
+			if tt.find != "" && !strings.Contains(buf.String(), tt.find) {
+				t.Errorf("Expected %q not found", tt.find)
+			}
+		})
+	}
+}
+
+// runMain creates a tmp folder with the templates files, the generated
+// package and the given go files, and executes "go run" in the folder.
+// The tmplFiles are added to the standard templates files.
+func runMain(t *testing.T, ctx *Context, tmplFiles, goFiles map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	c := *ctx
+	c.Dir = dir
+	ctx = &c
+
+	if err := writeTmplFolder(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range tmplFiles {
+		if err := writeFile(filepath.Join(dir, templateBaseDir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, fn := range []func(*Context, string) error{writeTemplates, writeMod} {
+		if err := fn(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range goFiles {
+		if err := writeFile(filepath.Join(dir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := execGoRun(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestReload: skipping test in short mode")
+	}
+
+	ctx := &Context{
+		PackageName:     "main",
+		Pages:           pages,
+		Templates:       templates,
+		TemplateBaseDir: templateBaseDir,
+	}
+
+	// the main func modifies the template files and reloads the templates
+	const text = `package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func check(want string) {
+	var b bytes.Buffer
+	if err := PageInh1.Execute(&b, nil); err != nil {
+		panic(err)
+	}
+	if !strings.Contains(b.String(), want) {
+		panic(fmt.Sprintf("got %q, want %q", b.String(), want))
+	}
+}
+
+func main() {
+	const path = "tmpl/inheritance/content1.tmpl"
+	InitTemplates()
+	check("content 1")
+
+	// invalid template: the current templates are kept
+	os.WriteFile(path, []byte("{{define \"content\"}}"), 0666)
+	err := Reload()
+	if err == nil || !strings.Contains(err.Error(), "template inh1:") {
+		panic(fmt.Sprintf("unexpected Reload error: %v", err))
+	}
+	check("content 1")
+
+	// valid template: the templates are replaced
+	os.WriteFile(path, []byte("{{define \"content\"}}content X{{end}}"), 0666)
+	if err := Reload(); err != nil {
+		panic(err)
+	}
+	check("content X")
+}
+`
+	runMain(t, ctx, nil, map[string]string{"main.go": text})
+}
+
+func TestLoadTemplates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestLoadTemplates: skipping test in short mode")
+	}
+
+	// the main func executes the pages before loading the templates, and
+	// removes some template files
+	const text = `package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	var b bytes.Buffer
+	if err := PageInh1.Execute(&b, nil); !noCache && !errors.Is(err, ErrTemplatesNotLoaded) {
+		panic(fmt.Sprintf("Execute before LoadTemplates: unexpected error %v", err))
+	}
+
+	if err := LoadTemplates(); err != nil {
+		panic(err)
+	}
+	if err := PageInh1.Execute(&b, nil); err != nil {
+		panic(err)
+	}
+
+	os.Remove("tmpl/inheritance/content1.tmpl")
+	os.Remove("tmpl/flat/page1.tmpl")
+	err := LoadTemplates()
+	if err == nil {
+		panic("LoadTemplates: expected error")
+	}
+	for _, s := range []string{"template flat:", "flat/page1.tmpl", "template inh1:", "inheritance/content1.tmpl"} {
+		if !strings.Contains(err.Error(), s) {
+			panic(fmt.Sprintf("LoadTemplates: error %q does not contain %q", err.Error(), s))
+		}
+	}
+	if strings.Contains(err.Error(), "template inh2:") {
+		panic(fmt.Sprintf("LoadTemplates: unexpected error %q", err.Error()))
+	}
+
+	defer func() {
+		if recover() == nil {
+			panic("InitTemplates: expected panic")
+		}
+	}()
+	InitTemplates()
+}
+`
+	for _, nocache := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nocache=%v", nocache), func(t *testing.T) {
+			ctx := &Context{
+				PackageName:     "main",
+				Pages:           pages,
+				Templates:       templates,
+				TemplateBaseDir: templateBaseDir,
+				NoCache:         nocache,
+			}
+			consts := fmt.Sprintf("package main\n\nconst noCache = %v\n", nocache)
+			runMain(t, ctx, nil, map[string]string{"main.go": text, "consts.go": consts})
+		})
+	}
+}
+
+func TestLazyInit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestLazyInit: skipping test in short mode")
+	}
+
+	const funcmap = `package main
+
+import "html/template"
+
+var funcMap = template.FuncMap{}
+`
+	// the main func executes the pages without loading the templates
+	const text = `package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+func execute(page PageEnum) error {
+	var b bytes.Buffer
+	return page.ExecuteContext(context.Background(), &b, nil)
+}
+
+func main() {
+	const path = "tmpl/flat/page1.tmpl"
+	content, _ := os.ReadFile(path)
+	os.Remove(path)
+
+	// the templates are created concurrently on first use
+	var wg sync.WaitGroup
+	for j := 0; j < 10; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := execute(PageInh1); err != nil {
+				panic(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the error is cached, even if the file is restored
+	if err := execute(PagePag1); err == nil || !strings.Contains(err.Error(), "template flat:") {
+		panic(fmt.Sprintf("Execute: unexpected error %v", err))
+	}
+	os.WriteFile(path, content, 0666)
+	if err := execute(PagePag2); err == nil {
+		panic("Execute: expected cached error")
+	}
+	if err := Preload(); err == nil || !strings.Contains(err.Error(), "template flat:") {
+		panic(fmt.Sprintf("Preload: unexpected error %v", err))
+	}
+
+	// Reload creates new templates
+	if err := Reload(); err != nil {
+		panic(err)
+	}
+	if err := Preload(); err != nil {
+		panic(err)
+	}
+	if err := execute(PagePag2); err != nil {
+		panic(err)
+	}
+}
+`
+	for _, funcMap := range []string{"", "funcMap"} {
+		t.Run(fmt.Sprintf("funcmap=%v", funcMap != ""), func(t *testing.T) {
+			ctx := &Context{
+				PackageName:     "main",
+				Pages:           pages,
+				Templates:       templates,
+				TemplateBaseDir: templateBaseDir,
+				FuncMap:         funcMap,
+				Init:            types.InitModeLazy,
+			}
+			goFiles := map[string]string{"main.go": text}
+			if funcMap != "" {
+				goFiles["funcmap.go"] = funcmap
+			}
+			runMain(t, ctx, nil, goFiles)
+		})
+	}
+}
+
+func TestTemplateKinds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestTemplateKinds: skipping test in short mode")
+	}
+
+	// the same template is escaped by html/template only
+	tmplFiles := map[string]string{
+		"kind/page.tmpl": `{{define "page"}}<b>{{ . }}</b>{{end}}`,
+	}
+	const funcmap = `package main
+
+import "html/template"
+
+var funcMap = template.FuncMap{}
+`
+	const text = `package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+func check(page PageEnum, want string) {
+	var b bytes.Buffer
+	if err := page.Execute(&b, "<x>"); err != nil {
+		panic(err)
+	}
+	if b.String() != want {
+		panic(fmt.Sprintf("Execute: got %q, want %q", b.String(), want))
+	}
+	b.Reset()
+	if err := page.ExecuteContext(context.Background(), &b, "<x>"); err != nil {
+		panic(err)
+	}
+	if b.String() != want {
+		panic(fmt.Sprintf("ExecuteContext: got %q, want %q", b.String(), want))
+	}
+}
+
+func main() {
+	InitTemplates()
+
+	check(PageHTML, "<b>&lt;x&gt;</b>")
+	check(PageText, "<b><x></b>")
+
+	if PageHTML.HTMLTemplate() == nil || PageHTML.TextTemplate() != nil {
+		panic("PageHTML: unexpected typed templates")
+	}
+	if PageText.TextTemplate() == nil || PageText.HTMLTemplate() != nil {
+		panic("PageText: unexpected typed templates")
+	}
+	if PageText.Template().Name() != "page.tmpl" {
+		panic(fmt.Sprintf("PageText: unexpected template name %q", PageText.Template().Name()))
+	}
+	if ct := PageText.ContentType(); ct != "text/plain; charset=utf-8" {
+		panic(fmt.Sprintf("PageText: unexpected content type %q", ct))
+	}
+}
+`
+	tmpl := map[string][]string{"html": {"kind/page.tmpl"}, "text": {"kind/page.tmpl"}}
+	pgs := map[string]Page{
+		"HTML": {Template: "html", Base: "page"},
+		"Text": {Template: "text", Base: "page"},
+	}
+
+	for _, nocache := range []bool{false, true} {
+		for _, funcMap := range []string{"", "funcMap"} {
+			t.Run(fmt.Sprintf("nocache=%v,funcmap=%v", nocache, funcMap != ""), func(t *testing.T) {
+				ctx := &Context{
+					PackageName:     "main",
+					Pages:           pgs,
+					Templates:       tmpl,
+					TemplateKinds:   map[string]string{"text": "text"},
+					TemplateBaseDir: templateBaseDir,
+					NoCache:         nocache,
+					FuncMap:         funcMap,
+					Init:            types.InitModeLazy,
+				}
+				goFiles := map[string]string{"main.go": text}
+				if funcMap != "" {
+					goFiles["funcmap.go"] = funcmap
+				}
+				runMain(t, ctx, tmplFiles, goFiles)
+			})
+		}
+	}
+}
+
+func TestTemplateKindsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		kinds   map[string]string
+		errLike string
+	}{
+		{
+			name:    "invalid kind",
+			kinds:   map[string]string{"flat": "xml"},
+			errLike: `template flat: invalid kind "xml"`,
+		},
+		{
+			name:    "undefined template",
+			kinds:   map[string]string{"mail": "text"},
+			errLike: `template kind of undefined template "mail"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &Context{Pages: pages, Templates: templates, TemplateKinds: tt.kinds, NoTemplateCheck: true}
+			err := ctx.Check()
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !errorLike(err, tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+		})
+	}
+}
+
+func TestExecuteContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestExecuteContext: skipping test in short mode")
+	}
+
+	// the template passes the context to a function of the funcMap
+	tmplFiles := map[string]string{
+		"ctx/page.tmpl": `{{define "page"}}user={{ user context }}{{end}}`,
+	}
+	const funcmap = `package main
+
+import (
+	"context"
+	"html/template"
+)
+
+type userKey struct{}
+
+var funcMap = template.FuncMap{
+	"user": func(ctx context.Context) string {
+		if s, ok := ctx.Value(userKey{}).(string); ok {
+			return s
+		}
+		return "none"
+	},
+}
+`
+	const text = `package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+func render(ctx context.Context) string {
+	var b bytes.Buffer
+	if err := PageCtx.ExecuteContext(ctx, &b, nil); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
+
+func main() {
+	InitTemplates()
+
+	// the context is available to the template functions
+	var wg sync.WaitGroup
+	for j := 0; j < 20; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			user := fmt.Sprint("u", j)
+			ctx := context.WithValue(context.Background(), userKey{}, user)
+			for k := 0; k < 10; k++ {
+				if got := render(ctx); got != "user="+user {
+					panic(fmt.Sprintf("ExecuteContext: got %q, want %q", got, "user="+user))
+				}
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	// Execute uses context.Background()
+	var b bytes.Buffer
+	if err := PageCtx.Execute(&b, nil); err != nil {
+		panic(err)
+	}
+	if b.String() != "user=none" {
+		panic(fmt.Sprintf("Execute: got %q", b.String()))
+	}
+
+	// canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Reset()
+	if err := PageInh1.ExecuteContext(ctx, &b, nil); !errors.Is(err, context.Canceled) {
+		panic(fmt.Sprintf("canceled context: unexpected error %v", err))
+	}
+	if b.Len() != 0 {
+		panic(fmt.Sprintf("canceled context: unexpected output %q", b.String()))
+	}
+}
+`
+	tmpl := map[string][]string{"ctx": {"ctx/page.tmpl"}}
+	for k, v := range templates {
+		tmpl[k] = v
+	}
+	pgs := map[string]Page{"Ctx": {Template: "ctx", Base: "page"}}
+	for k, v := range pages {
+		pgs[k] = v
+	}
+
+	for _, nocache := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nocache=%v", nocache), func(t *testing.T) {
+			ctx := &Context{
+				PackageName:     "main",
+				Pages:           pgs,
+				Templates:       tmpl,
+				TemplateBaseDir: templateBaseDir,
+				FuncMap:         "funcMap",
+				NoCache:         nocache,
+			}
+			runMain(t, ctx, tmplFiles, map[string]string{"main.go": text, "funcmap.go": funcmap})
+		})
+	}
+}
+
+func TestBufferedRender(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestBufferedRender: skipping test in short mode")
+	}
+
+	// the page fails after writing part of its output if data has less
+	// than two items
+	tmplFiles := map[string]string{
+		"buf/page.tmpl": `{{define "page"}}start {{ index . 1 }}{{end}}`,
+	}
+	const text = `package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+func check(name string, fn func(*bytes.Buffer, any) error, partial string) {
+	var b bytes.Buffer
+	if err := fn(&b, []int{0, 7}); err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	if b.String() != "start 7" {
+		panic(fmt.Sprintf("%s: got %q, want %q", name, b.String(), "start 7"))
+	}
+
+	b.Reset()
+	if err := fn(&b, []int{0}); err == nil {
+		panic(fmt.Sprintf("%s: expected error", name))
+	}
+	if b.String() != partial {
+		panic(fmt.Sprintf("%s: got %q on error, want %q", name, b.String(), partial))
+	}
+}
+
+func main() {
+	InitTemplates()
+
+	// Render never writes a partial output
+	check("Render", func(b *bytes.Buffer, data any) error {
+		return PageBuf.Render(b, data)
+	}, "")
+	check("RenderString", func(b *bytes.Buffer, data any) error {
+		s, err := PageBuf.RenderString(data)
+		b.WriteString(s)
+		return err
+	}, "")
+
+	// Execute writes a partial output unless it is buffered
+	var partial string
+	if !bufferedExecute {
+		partial = "start "
+	}
+	check("Execute", func(b *bytes.Buffer, data any) error {
+		return PageBuf.Execute(b, data)
+	}, partial)
+	check("ExecuteContext", func(b *bytes.Buffer, data any) error {
+		return PageBuf.ExecuteContext(context.Background(), b, data)
+	}, partial)
+}
+`
+	tmpl := map[string][]string{"buf": {"buf/page.tmpl"}}
+	pgs := map[string]Page{"Buf": {Template: "buf", Base: "page"}}
+
+	for _, nocache := range []bool{false, true} {
+		for _, buffered := range []bool{false, true} {
+			t.Run(fmt.Sprintf("nocache=%v,buffered_execute=%v", nocache, buffered), func(t *testing.T) {
+				ctx := &Context{
+					PackageName:     "main",
+					Pages:           pgs,
+					Templates:       tmpl,
+					TemplateBaseDir: templateBaseDir,
+					NoCache:         nocache,
+					BufferedRender:  true,
+					BufferedExecute: buffered,
+				}
+				consts := fmt.Sprintf("package main\n\nconst bufferedExecute = %v\n", buffered)
+				runMain(t, ctx, tmplFiles, map[string]string{"main.go": text, "consts.go": consts})
+			})
+		}
+	}
+}
+
+func TestHTTPHandlers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestHTTPHandlers: skipping test in short mode")
+	}
+
+	tmplFiles := map[string]string{
+		"http/page.tmpl": `{{define "user"}}user {{ .Name }}{{end}}{{define "home"}}home{{end}}`,
+		"http/feed.tmpl": `<feed/>`,
+	}
+	const text = `package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func data(page PageEnum, r *http.Request) (any, error) {
+	if page != PageUser {
+		return nil, nil
+	}
+	id := r.PathValue("id")
+	if id == "0" {
+		return nil, errors.New("user not found")
+	}
+	if id == "1" {
+		// the template fails: Name is not a field of a string
+		return "no name", nil
+	}
+	return map[string]string{"Name": id}, nil
+}
+
+func get(mux http.Handler, method, url string, code int, body string) {
+	getContentType(mux, method, url, code, body, "text/html; charset=utf-8")
+}
+
+func getContentType(mux http.Handler, method, url string, code int, body, contentType string) {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+	if w.Code != code {
+		panic(fmt.Sprintf("%s %s: got status %d, want %d", method, url, w.Code, code))
+	}
+	if code != http.StatusOK {
+		return
+	}
+	if w.Body.String() != body {
+		panic(fmt.Sprintf("%s %s: got body %q, want %q", method, url, w.Body.String(), body))
+	}
+	if ct := w.Header().Get("Content-Type"); ct != contentType {
+		panic(fmt.Sprintf("%s %s: got Content-Type %q", method, url, ct))
+	}
+}
+
+func main() {
+	InitTemplates()
+
+	mux := http.NewServeMux()
+	Register(mux, data)
+
+	get(mux, "GET", "/", http.StatusOK, "home")
+	get(mux, "GET", "/users/abc", http.StatusOK, "user abc")
+	get(mux, "POST", "/users/abc", http.StatusMethodNotAllowed, "")
+	get(mux, "GET", "/users/0", http.StatusInternalServerError, "")
+	get(mux, "GET", "/users/1", http.StatusInternalServerError, "")
+	get(mux, "GET", "/other", http.StatusNotFound, "")
+	getContentType(mux, "GET", "/feed", http.StatusOK, "<feed/>", "application/atom+xml; charset=utf-8")
+
+	// ad-hoc handler without data
+	get(PageHome.Handler(nil), "GET", "/any", http.StatusOK, "home")
+}
+`
+	tmpl := map[string][]string{"http": {"http/page.tmpl"}, "feed": {"http/feed.tmpl"}}
+	pgs := map[string]Page{
+		"Home":  {Template: "http", Base: "home", Route: "/{$}", Method: "GET"},
+		"User":  {Template: "http", Base: "user", Route: "GET /users/{id}"},
+		"Other": {Template: "http", Base: "home"},
+		"Feed":  {Template: "feed", Route: "GET /feed", ContentType: "application/atom+xml; charset=utf-8"},
+	}
+
+	for _, nocache := range []bool{false, true} {
+		for _, buffered := range []bool{false, true} {
+			t.Run(fmt.Sprintf("nocache=%v,buffered_render=%v", nocache, buffered), func(t *testing.T) {
+				ctx := &Context{
+					PackageName:     "main",
+					Pages:           pgs,
+					Templates:       tmpl,
+					TemplateBaseDir: templateBaseDir,
+					NoCache:         nocache,
+					BufferedRender:  buffered,
+				}
+				runMain(t, ctx, tmplFiles, map[string]string{"main.go": text})
+			})
+		}
+	}
+}
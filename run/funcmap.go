@@ -0,0 +1,87 @@
+package run
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// contextFunc is the name of the template function bound to the context of
+// ExecuteContext, that overrides a function with the same name of FuncMap.
+const contextFunc = "context"
+
+// checkFuncMap returns an error if the FuncMap variable, looked for in the
+// source files of the PackageDir folder, defines the "context" function.
+// Only the keys of the composite literal that initializes the variable are
+// checked. The files generated by gentmpl, the test files and the files that
+// cannot be parsed are ignored.
+func (ctx *Context) checkFuncMap() error {
+	if ctx.FuncMap == "" || ctx.PackageDir == "" {
+		return nil
+	}
+	// the folder of a new package may not exist yet
+	entries, _ := os.ReadDir(ctx.PackageDir)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(ctx.PackageDir, name)
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.HasPrefix(bytes.TrimSpace(content), []byte(generatedMarker)) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		if pos := funcMapKey(f, ctx.FuncMap, contextFunc); pos.IsValid() {
+			return ctx.errorf("func_map", "func_map: %s defines the %q function, that is reserved for ExecuteContext (%s)",
+				ctx.FuncMap, contextFunc, fset.Position(pos))
+		}
+	}
+	return nil
+}
+
+// funcMapKey returns the position of the given key in the composite literal
+// that initializes the package level variable with given name, if any.
+func funcMapKey(f *ast.File, name, key string) token.Pos {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, id := range vs.Names {
+				if id.Name != name || i >= len(vs.Values) {
+					continue
+				}
+				lit, ok := vs.Values[i].(*ast.CompositeLit)
+				if !ok {
+					continue
+				}
+				for _, elt := range lit.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					bl, ok := kv.Key.(*ast.BasicLit)
+					if !ok || bl.Kind != token.STRING {
+						continue
+					}
+					if s, err := strconv.Unquote(bl.Value); err == nil && s == key {
+						return bl.Pos()
+					}
+				}
+			}
+		}
+	}
+	return token.NoPos
+}
@@ -0,0 +1,29 @@
+package run
+
+import "io"
+
+// GoldenTestFile is the name of the golden test file of the generated
+// package.
+const GoldenTestFile = "golden_gen_test.go"
+
+// WriteGoldenTest prints to writer the golden test file of the generated
+// package, to be written in the folder of the package.
+//
+// The TestPagesGolden test of the file renders each page with the data
+// decoded from the <GoldenDir>/<Page>.json fixture, using the data type of
+// the page if any, and compares the output with the <GoldenDir>/<Page>.golden
+// file. A missing fixture renders the page with the zero value of its data.
+// Running the test with the -update flag rewrites the golden files, so that
+// the changes of the output can be reviewed as a diff.
+func (ctx *Context) WriteGoldenTest(w io.Writer) error {
+	data, err := ctx.checkAndPrepare()
+	if err != nil {
+		return err
+	}
+	p, err := ctx.generate(getTemplate(), GoldenTestFile, data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(p)
+	return err
+}
@@ -0,0 +1,90 @@
+package run
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// execGoTest runs go test in the dir folder with the given arguments.
+func execGoTest(dir string, args ...string) error {
+	cmd := exec.Command("go", append([]string{"test"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(string(out))
+	}
+	return nil
+}
+
+func TestWriteGoldenTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestWriteGoldenTest: skipping test in short mode")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"tmpl/greet.tmpl":   `{{define "greet"}}Hello {{.Name}}!{{end}}`,
+		"tmpl/plain.tmpl":   `plain {{.}}`,
+		"models/models.go":  "package models\n\ntype User struct{ Name string }\n",
+		"golden/Greet.json": `{"Name": "gopher"}`,
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, name), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &Context{
+		PackageName:     "templates",
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+		GoldenTests:     true,
+		GoldenDir:       "golden",
+		Templates: map[string][]string{
+			"greet": {"greet.tmpl"},
+			"plain": {"plain.tmpl"},
+		},
+		Pages: map[string]Page{
+			"Greet": {Template: "greet", Base: "greet", Data: "*models.User", Import: "example.com/test/gentmpl/models"},
+			"Plain": {Template: "plain"},
+		},
+	}
+	if err := writeMod(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.WritePackageDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// missing golden files
+	if err := execGoTest(dir); err == nil || !strings.Contains(err.Error(), "-update") {
+		t.Fatalf("go test: expected missing golden file error, found %v", err)
+	}
+
+	if err := execGoTest(dir, "-update"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"Greet": "Hello gopher!", "Plain": "plain "} {
+		got, err := os.ReadFile(filepath.Join(dir, "golden", name+".golden"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s.golden = %q, want %q", name, got, want)
+		}
+	}
+	if err := execGoTest(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// a change of the output is reported
+	if err := writeFile(filepath.Join(dir, "golden", "Greet.golden"), "Hello!"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGoTest(dir); err == nil || !strings.Contains(err.Error(), "output differs from") {
+		t.Errorf("go test: expected output differs error, found %v", err)
+	}
+}
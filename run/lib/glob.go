@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IsGlob returns true if the item is a glob pattern.
+func IsGlob(item string) bool {
+	return strings.ContainsAny(item, "*?[")
+}
+
+// Glob returns the (sorted) names of the files of fsys matching the pattern.
+// The pattern syntax is the one of path.Match, with the addition of the "**"
+// path element that matches zero or more directories.
+// Example: "partials/**/*.tmpl".
+func Glob(fsys fs.FS, pattern string) ([]string, error) {
+	elems := strings.Split(pattern, "/")
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %s", pattern, err.Error())
+		}
+	}
+
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || name == "." {
+			return nil
+		}
+		if matchElems(elems, strings.Split(name, "/")) {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchElems reports whether the path elements match the pattern elements.
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		return matchElems(pattern[1:], elems) ||
+			(len(elems) > 0 && matchElems(pattern, elems[1:]))
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], elems[0])
+	return ok && matchElems(pattern[1:], elems[1:])
+}
+
+// ExpandGlobs returns a copy of the items where each glob pattern is replaced
+// by the files returned by the glob func. The files that are already present
+// are not added again. It returns an error if a pattern matches no files.
+func ExpandGlobs(items []string, glob func(pattern string) ([]string, error)) ([]string, error) {
+	var res []string
+	found := map[string]struct{}{}
+
+	add := func(file string) {
+		if _, ok := found[file]; !ok {
+			found[file] = struct{}{}
+			res = append(res, file)
+		}
+	}
+
+	for _, item := range items {
+		if !IsGlob(item) {
+			add(item)
+			continue
+		}
+		files, err := glob(item)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("pattern %q matches no files", item)
+		}
+		for _, file := range files {
+			add(file)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.tmpl":             {},
+		"partials/a.tmpl":         {},
+		"partials/b.html":         {},
+		"partials/x/c.tmpl":       {},
+		"partials/x/y/d.tmpl":     {},
+		"partials/x/y/README.txt": {},
+		"pages/p1.tmpl":           {},
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{pattern: "*.tmpl", want: []string{"layout.tmpl"}},
+		{pattern: "partials/*.tmpl", want: []string{"partials/a.tmpl"}},
+		{pattern: "partials/**/*.tmpl", want: []string{"partials/a.tmpl", "partials/x/c.tmpl", "partials/x/y/d.tmpl"}},
+		{pattern: "**/*.tmpl", want: []string{"layout.tmpl", "pages/p1.tmpl", "partials/a.tmpl", "partials/x/c.tmpl", "partials/x/y/d.tmpl"}},
+		{pattern: "partials/**", want: []string{"partials/a.tmpl", "partials/b.html", "partials/x/c.tmpl", "partials/x/y/README.txt", "partials/x/y/d.tmpl"}},
+		{pattern: "p?ges/p[0-9].tmpl", want: []string{"pages/p1.tmpl"}},
+		{pattern: "missing/*.tmpl"},
+		{pattern: "partials/[.tmpl", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := Glob(fsys, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Glob(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+			if !checkEqual(got, tt.want) {
+				t.Errorf("Glob(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	glob := func(pattern string) ([]string, error) {
+		switch pattern {
+		case "p/*.tmpl":
+			return []string{"p/a.tmpl", "p/b.tmpl"}, nil
+		case "e/*.tmpl":
+			return nil, nil
+		}
+		return nil, errors.New("glob error")
+	}
+
+	tests := []struct {
+		name    string
+		items   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no globs", items: []string{"a.tmpl", "b.tmpl"}, want: []string{"a.tmpl", "b.tmpl"}},
+		{name: "glob", items: []string{"base.tmpl", "p/*.tmpl", "z.tmpl"}, want: []string{"base.tmpl", "p/a.tmpl", "p/b.tmpl", "z.tmpl"}},
+		{name: "duplicates", items: []string{"p/b.tmpl", "p/*.tmpl"}, want: []string{"p/b.tmpl", "p/a.tmpl"}},
+		{name: "no match", items: []string{"e/*.tmpl"}, wantErr: true},
+		{name: "glob error", items: []string{"x/*.tmpl"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandGlobs(tt.items, glob)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandGlobs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !checkEqual(got, tt.want) {
+				t.Errorf("ExpandGlobs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
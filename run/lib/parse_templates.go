@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"sort"
+	"text/template/parse"
+)
+
+// TemplateFile contains the information about a template file found
+// parsing its content.
+type TemplateFile struct {
+	// Name of the top-level template of the file.
+	Name string
+	// Names of the templates defined in the file, including the top-level
+	// template (sorted).
+	Defined []string
+	// Mapping from the name of each template defined in the file to the
+	// names of the templates it references with {{template "name"}} (sorted).
+	References map[string][]string
+	// Names of the defined templates that contain only spaces, as the
+	// top-level template of a file with only {{define}} actions (sorted).
+	Empty []string
+}
+
+// ParseTemplateFile parses the content of a template file.
+// The name of the top-level template is the given name, as in
+// template.ParseFiles that uses the base name of the file.
+// The functions called in the template are not checked, so that the file can
+// be parsed without knowing the template.FuncMap used at runtime.
+func ParseTemplateFile(name, text string) (*TemplateFile, error) {
+	treeSet := map[string]*parse.Tree{}
+
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(text, "", "", treeSet); err != nil {
+		return nil, err
+	}
+
+	tf := &TemplateFile{
+		Name:       name,
+		References: map[string][]string{},
+	}
+	for n, tree := range treeSet {
+		tf.Defined = append(tf.Defined, n)
+		if parse.IsEmptyTree(tree.Root) {
+			tf.Empty = append(tf.Empty, n)
+		}
+
+		refs := map[string]struct{}{}
+		walkTemplateNodes(tree.Root, func(tn *parse.TemplateNode) {
+			refs[tn.Name] = struct{}{}
+		})
+		names := make([]string, 0, len(refs))
+		for ref := range refs {
+			names = append(names, ref)
+		}
+		sort.Strings(names)
+		tf.References[n] = names
+	}
+	sort.Strings(tf.Defined)
+	sort.Strings(tf.Empty)
+
+	return tf, nil
+}
+
+// walkTemplateNodes calls fn for each {{template}} node found in the tree
+// rooted at node.
+func walkTemplateNodes(node parse.Node, fn func(*parse.TemplateNode)) {
+	walkBranch := func(b *parse.BranchNode) {
+		walkTemplateNodes(b.List, fn)
+		walkTemplateNodes(b.ElseList, fn)
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateNodes(child, fn)
+		}
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		fn(n)
+	}
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplateFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		defined    []string
+		references map[string][]string
+		empty      []string
+		wantErr    bool
+	}{
+		{
+			name:       "base.tmpl",
+			text:       `<html><body>{{template "content" .}}</body></html>`,
+			defined:    []string{"base.tmpl"},
+			references: map[string][]string{"base.tmpl": {"content"}},
+		},
+		{
+			name:    "pages.tmpl",
+			text:    `{{define "page-2"}}{{template "header"}}2{{template "footer"}}{{end}}{{define "page-3"}}3{{end}}`,
+			defined: []string{"page-2", "page-3", "pages.tmpl"},
+			references: map[string][]string{
+				"page-2":     {"footer", "header"},
+				"page-3":     {},
+				"pages.tmpl": {},
+			},
+			empty: []string{"pages.tmpl"},
+		},
+		{
+			name:    "funcs.tmpl",
+			text:    `{{define "f"}}{{ weekday .Now | ToUpper }}{{end}}`,
+			defined: []string{"f", "funcs.tmpl"},
+			references: map[string][]string{
+				"f":          {},
+				"funcs.tmpl": {},
+			},
+			empty: []string{"funcs.tmpl"},
+		},
+		{
+			name:    "nested.tmpl",
+			text:    `{{if .A}}{{template "a"}}{{else}}{{range .B}}{{template "b" .}}{{else}}{{with .C}}{{template "c"}}{{end}}{{end}}{{end}}{{block "d" .}}{{template "a"}}{{end}}`,
+			defined: []string{"d", "nested.tmpl"},
+			references: map[string][]string{
+				"d":           {"a"},
+				"nested.tmpl": {"a", "b", "c", "d"},
+			},
+		},
+		{
+			name:    "error.tmpl",
+			text:    `{{define "x"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTemplateFile(tt.name, tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplateFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Name != tt.name {
+				t.Errorf("ParseTemplateFile() Name = %q, want %q", got.Name, tt.name)
+			}
+			if !checkEqual(got.Defined, tt.defined) {
+				t.Errorf("ParseTemplateFile() Defined = %v, want %v", got.Defined, tt.defined)
+			}
+			if !reflect.DeepEqual(got.References, tt.references) {
+				t.Errorf("ParseTemplateFile() References = %v, want %v", got.References, tt.references)
+			}
+			if !checkEqual(got.Empty, tt.empty) {
+				t.Errorf("ParseTemplateFile() Empty = %v, want %v", got.Empty, tt.empty)
+			}
+		})
+	}
+}
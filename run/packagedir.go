@@ -0,0 +1,210 @@
+package run
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// packageFileNames are the names of the files of the package written by
+// WritePackageDir. Each file is generated by the template with the same
+// name.
+var packageFileNames = []string{
+	"pages_gen.go",   // enums and lookup tables
+	"loader_gen.go",  // creation and loading of the templates
+	"execute_gen.go", // execution of the pages
+}
+
+// generatedMarker is the start of the first line of the files generated by
+// gentmpl.
+const generatedMarker = "// Generated by gentmpl;"
+
+// GeneratedFile is a file of the generated package.
+type GeneratedFile struct {
+	Name    string // base name of the file (ex: "pages_gen.go")
+	Content []byte
+}
+
+// PackageFiles returns the generated package split in many files, instead
+// of the single file written by WritePackage:
+//   - pages_gen.go: the enums and the lookup tables of the pages and
+//     templates;
+//   - loader_gen.go: the embedded files and the creation and loading of the
+//     templates (InitTemplates, LoadTemplates, ...);
+//   - execute_gen.go: the execution of the pages (Execute, Render, the http
+//     handlers, ...).
+//
+// If GoldenTests is true, the golden test file (see WriteGoldenTest) is
+// also returned.
+// Each file imports only the packages it uses.
+func (ctx *Context) PackageFiles() ([]GeneratedFile, error) {
+	data, err := ctx.checkAndPrepare()
+	if err != nil {
+		return nil, err
+	}
+	names := packageFileNames
+	if ctx.GoldenTests {
+		names = append(names[:len(names):len(names)], GoldenTestFile)
+	}
+	t := getTemplate()
+	files := make([]GeneratedFile, len(names))
+	for j, name := range names {
+		p, err := ctx.generate(t, name, data)
+		if err != nil {
+			return nil, err
+		}
+		files[j] = GeneratedFile{Name: name, Content: p}
+	}
+	return files, nil
+}
+
+// WritePackageDir writes the files returned by PackageFiles to the dir
+// folder, creating it if needed, and removes the stale *_gen.go and
+// *_gen_test.go files previously generated by gentmpl in the folder.
+// No file is written if the package cannot be generated.
+func (ctx *Context) WritePackageDir(dir string) error {
+	files, err := ctx.PackageFiles()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Content, 0660); err != nil {
+			return err
+		}
+	}
+	stale, err := StaleFiles(dir, files)
+	if err != nil {
+		return err
+	}
+	for _, p := range stale {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StaleFiles returns the paths of the *_gen.go and *_gen_test.go files of
+// the dir folder generated by gentmpl that are not among the given files.
+func StaleFiles(dir string, files []GeneratedFile) ([]string, error) {
+	written := map[string]struct{}{}
+	for _, f := range files {
+		written[f.Name] = struct{}{}
+	}
+	var paths []string
+	for _, pattern := range []string{"*_gen.go", "*_gen_test.go"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	var stale []string
+	for _, p := range paths {
+		if _, ok := written[filepath.Base(p)]; ok {
+			continue
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(content), []byte(generatedMarker)) {
+			stale = append(stale, p)
+		}
+	}
+	return stale, nil
+}
+
+// pruneImports removes from the Go source the lines of the imports that are
+// not used, and the import declarations left empty.
+// An import is used if its name is the operand of a selector and is not
+// declared in the file: the package-level identifiers of the generated
+// package cannot be named as an import (see reservedNames).
+// The source is returned unchanged if it cannot be parsed.
+func pruneImports(src []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return src
+	}
+
+	// names used as package qualifiers: the identifiers that are not
+	// resolved to a declaration of the file, as the local variables (ex:
+	// page.Execute, where page is the receiver, does not use the page
+	// package)
+	used := map[string]struct{}{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+
+	isUsed := func(spec *ast.ImportSpec) bool {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		_, ok := used[name]
+		return ok || name == "_" || name == "."
+	}
+
+	// offsets of the lines to remove
+	type span struct{ start, end int }
+	var spans []span
+	addLines := func(node ast.Node) {
+		start := fset.Position(node.Pos()).Offset
+		end := fset.Position(node.End()).Offset
+		start = bytes.LastIndexByte(src[:start], '\n') + 1
+		if j := bytes.IndexByte(src[end:], '\n'); j >= 0 {
+			end += j + 1
+		} else {
+			end = len(src)
+		}
+		spans = append(spans, span{start, end})
+	}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		var unused []ast.Spec
+		for _, spec := range gen.Specs {
+			if !isUsed(spec.(*ast.ImportSpec)) {
+				unused = append(unused, spec)
+			}
+		}
+		if len(unused) == len(gen.Specs) {
+			addLines(gen)
+			continue
+		}
+		for _, spec := range unused {
+			addLines(spec)
+		}
+	}
+	if len(spans) == 0 {
+		return src
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var buf bytes.Buffer
+	last := 0
+	for _, s := range spans {
+		buf.Write(src[last:s.start])
+		last = s.end
+	}
+	buf.Write(src[last:])
+	return buf.Bytes()
+}
@@ -0,0 +1,298 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/run/types"
+)
+
+func TestWritePackageDir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestWritePackageDir: skipping test in short mode")
+	}
+
+	const mainGo = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := LoadTemplates(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := PagePag1.Execute(os.Stdout, nil); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+`
+	tests := []struct {
+		name string
+		ctx  *Context
+	}{
+		{
+			name: "default",
+			ctx:  &Context{},
+		},
+		{
+			name: "embed funcmap lazy",
+			ctx: &Context{
+				AssetManager:   types.AssetManagerEmbed,
+				FuncMap:        "funcMap",
+				Init:           types.InitModeLazy,
+				BufferedRender: true,
+			},
+		},
+		{
+			name: "nocache mixed routes",
+			ctx: &Context{
+				NoCache:       true,
+				TemplateKinds: map[string]string{"inh1": "text"},
+				Pages: map[string]Page{
+					"Pag1": {Template: "flat", Base: "page-1", Route: "GET /pag1"},
+					"Inh1": {Template: "inh1", Data: "map[string]string"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ctx := tt.ctx
+			ctx.PackageName = "main"
+			ctx.TemplateBaseDir = templateBaseDir
+			ctx.Templates = templates
+			ctx.Dir = dir
+			if ctx.Pages == nil {
+				ctx.Pages = pages
+			}
+
+			if err := writeTmplFolder(ctx, dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := ctx.WritePackageDir(dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, fn := range []func(*Context, string) error{writeFuncmap, writeMod} {
+				if err := fn(ctx, dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeFile(filepath.Join(dir, "main.go"), mainGo); err != nil {
+				t.Fatal(err)
+			}
+			if err := execGoRun(dir); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestWritePackageDirLocalNames(t *testing.T) {
+	if testing.Short() {
+		t.Skip("TestWritePackageDirLocalNames: skipping test in short mode")
+	}
+
+	// the package of the data type is named as the page receiver and the
+	// local variables of the generated code
+	dir := t.TempDir()
+	ctx := &Context{
+		PackageName:     "out",
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+		Templates:       templates,
+		Pages: map[string]Page{
+			"Pag1": {Template: "flat", Base: "page-1", Data: "*page.User", Import: "example.com/test/gentmpl/page"},
+			"Pag2": {Template: "flat", Base: "page-2", Data: "*cache.Entry", Import: "example.com/test/gentmpl/cache"},
+		},
+	}
+	for _, fn := range []func(*Context, string) error{writeTmplFolder, writeMod} {
+		if err := fn(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range map[string]string{
+		"page/page.go":   "package page\n\ntype User struct{ Name string }\n",
+		"cache/cache.go": "package cache\n\ntype Entry struct{ Key string }\n",
+	} {
+		if err := writeFile(filepath.Join(dir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ctx.WritePackageDir(filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "vet", "./out")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet: %s\n%s", err, out)
+	}
+}
+
+func TestPackageFiles(t *testing.T) {
+	ctx := &Context{
+		Pages:           pages,
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	files, err := ctx.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		find    []string
+		notFind []string
+	}{
+		{
+			name:    "pages_gen.go",
+			find:    []string{"const templatesLen = 3", "func (page PageEnum) Base() string", `"fmt"`, `"database/sql/driver"`, "func ParsePage("},
+			notFind: []string{`"io"`, `"html/template"`, "func LoadTemplates() error", "func (page PageEnum) Execute("},
+		},
+		{
+			name:    "loader_gen.go",
+			find:    []string{`"html/template"`, `"sync/atomic"`, "func LoadTemplates() error"},
+			notFind: []string{`"io"`, "func (page PageEnum) Base() string", "func (page PageEnum) Execute("},
+		},
+		{
+			name:    "execute_gen.go",
+			find:    []string{`"context"`, `"io"`, "func (page PageEnum) Execute(", "func RenderInh2("},
+			notFind: []string{`"sync/atomic"`, `"path/filepath"`, `"database/sql/driver"`, "func LoadTemplates() error"},
+		},
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if want := []string{"pages_gen.go", "loader_gen.go", "execute_gen.go"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("PackageFiles() names = %q, want %q", names, want)
+	}
+	ctx.GoldenTests = true
+	if golden, err := ctx.PackageFiles(); err != nil || len(golden) != 4 || golden[3].Name != GoldenTestFile {
+		t.Errorf("PackageFiles() with GoldenTests: expected the %s file, found %d files, error %v", GoldenTestFile, len(golden), err)
+	}
+	for j, tt := range tests {
+		content := string(files[j].Content)
+		if !strings.HasPrefix(content, generatedMarker) {
+			t.Errorf("%s: expected prefix %q", tt.name, generatedMarker)
+		}
+		for _, find := range tt.find {
+			if !strings.Contains(content, find) {
+				t.Errorf("%s: expected %q not found", tt.name, find)
+			}
+		}
+		for _, find := range tt.notFind {
+			if strings.Contains(content, find) {
+				t.Errorf("%s: unexpected %q found", tt.name, find)
+			}
+		}
+	}
+}
+
+func TestWritePackageDirStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// generated by a previous version
+		"old_gen.go":      generatedMarker + " *** DO NOT EDIT ***\npackage templates\n",
+		"old_gen_test.go": generatedMarker + " *** DO NOT EDIT ***\npackage templates\n",
+		// not generated by gentmpl
+		"user_gen.go": "// Code generated by stringer. DO NOT EDIT.\npackage templates\n",
+		"user.go":     "package templates\n",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, name), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &Context{
+		Pages:           pages,
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	if err := ctx.WritePackageDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{"execute_gen.go", "loader_gen.go", "pages_gen.go", "user.go", "user_gen.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+
+	// no file is written if the package cannot be generated
+	ctx.Pages = nil
+	if err := ctx.WritePackageDir(filepath.Join(dir, "new")); err == nil {
+		t.Errorf("expected error; no error found")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+		t.Errorf("expected folder not created, found error %v", err)
+	}
+}
+
+func TestPruneImports(t *testing.T) {
+	src := `package p
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"path/filepath"
+	_ "embed"
+)
+
+func f(w io.Writer) *htmltemplate.Template {
+	fmt.Fprintln(w)
+	return nil
+}
+`
+	want := `package p
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	_ "embed"
+)
+
+func f(w io.Writer) *htmltemplate.Template {
+	fmt.Fprintln(w)
+	return nil
+}
+`
+	if got := string(pruneImports([]byte(src))); got != want {
+		t.Errorf("pruneImports():\ngot  %s\nwant %s", got, want)
+	}
+
+	// the receiver page is not the page package
+	local := "package p\n\nimport (\n\t\"example.com/page\"\n\t\"io\"\n)\n\nfunc (page T) f(w io.Writer) { page.Execute(w) }\n"
+	if got, want := string(pruneImports([]byte(local))), "package p\n\nimport (\n\t\"io\"\n)\n\nfunc (page T) f(w io.Writer) { page.Execute(w) }\n"; got != want {
+		t.Errorf("pruneImports() = %q, want %q", got, want)
+	}
+
+	unused := "package p\n\nimport (\n\t\"fmt\"\n)\n\nvar x = 1\n"
+	if got, want := string(pruneImports([]byte(unused))), "package p\n\n\nvar x = 1\n"; got != want {
+		t.Errorf("pruneImports() = %q, want %q", got, want)
+	}
+
+	invalid := "package p\nimport \"fmt\"\nfunc {"
+	if got := string(pruneImports([]byte(invalid))); got != invalid {
+		t.Errorf("pruneImports() of invalid source = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,262 @@
+package run
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// typedPage contains the information needed to generate the type-safe render
+// function of a page.
+type typedPage struct {
+	Name string // page name
+	Data string // Go type of the page data
+}
+
+// importSpec is an import declaration of the generated package.
+type importSpec struct {
+	Name string // package name used in the generated code
+	Path string // import path
+}
+
+// generatedImports contains the names of the packages imported by the
+// generated code.
+var generatedImports = []string{"atomic", "bytes", "context", "driver", "embed", "errors", "filepath", "fmt", "htmltemplate", "http", "io", "sync", "template", "texttemplate"}
+
+// generatedIdents contains the package-level identifiers declared by the
+// generated code, except the PageEnum and TemplateEnum types, the page
+// constants and the Render<Page> functions.
+var generatedIdents = []string{
+	"AllPages", "ErrTemplatesNotLoaded", "Executor", "InitTemplates", "LoadTemplates", "PageInfo",
+	"PagesSeq", "ParsePage", "Preload", "Register", "Reload", "UnknownPageError",
+	"bindContext", "bufferPool", "cloneTemplate", "content", "contextFuncMap", "contextWriter",
+	"file2path", "files2paths", "getBuffer", "init", "mTemplates", "maxPooledBufferSize",
+	"pageNames", "pagesLen", "parseTemplates", "putBuffer", "routes", "templateCache", "templatesLen",
+}
+
+// goldenImports contains the names of the packages imported by the golden
+// test file.
+var goldenImports = []string{"bytes", "errors", "filepath", "flag", "fs", "json", "os", "testing"}
+
+// goldenIdents contains the package-level identifiers declared by the
+// golden test file.
+var goldenIdents = []string{"TestPagesGolden", "goldenData", "goldenDir", "updateGolden"}
+
+// reservedNames returns the names declared by the generated package, in the
+// package block or in the file block of its files, that the page constants
+// and the package names of the data types cannot use. Each name is mapped to
+// the description of its declaration, used in the error messages.
+// The names of the golden test file are reserved only if GoldenTests is
+// true.
+func (ctx *Context) reservedNames() map[string]string {
+	names := map[string]string{}
+	add := func(what string, a ...string) {
+		for _, name := range a {
+			names[name] = what
+		}
+	}
+	if ctx.GoldenTests {
+		add("the imports of the golden test file", goldenImports...)
+		add("an identifier of the golden test file", goldenIdents...)
+	}
+	add("the imports of the generated code", generatedImports...)
+	add("an identifier of the generated package", generatedIdents...)
+	add("an identifier of the generated package",
+		nvl(ctx.PageEnumType, defaultPageEnumType), nvl(ctx.TemplateEnumType, defaultTemplateEnumType))
+	if ctx.FuncMap != "" {
+		add("an identifier of the generated package", ctx.FuncMap)
+	}
+	for name, page := range ctx.Pages {
+		if page.Data != "" {
+			add("an identifier of the generated package", renderName(name))
+		}
+	}
+	return names
+}
+
+// pageDataTypes checks the data type and import attributes of the given pages.
+// It returns the pages with a data type and the imports needed by them.
+func (ctx *Context) pageDataTypes(pageNames []string) ([]typedPage, []importSpec, error) {
+	var typed []typedPage
+
+	// mapping from package name to import path
+	name2path := map[string]string{}
+	// mapping from package name to the first page that uses it
+	name2page := map[string]string{}
+	// names declared by the generated package
+	reserved := ctx.reservedNames()
+
+	for _, pageName := range pageNames {
+		page := ctx.Pages[pageName]
+
+		if page.Data == "" {
+			if page.Import != "" {
+				return nil, nil, ctx.errorf(pageKey(pageName), "page %s: import %q without data type", pageName, page.Import)
+			}
+			continue
+		}
+
+		qualifiers, err := parseDataType(page.Data)
+		if err != nil {
+			return nil, nil, ctx.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+		}
+
+		if page.Import != "" {
+			if err := checkImportPath(page.Import); err != nil {
+				return nil, nil, ctx.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+			}
+		}
+
+		switch {
+		case len(qualifiers) > 1:
+			return nil, nil, ctx.errorf(pageKey(pageName), "page %s: data type %q references more than one package: %s",
+				pageName, page.Data, strings.Join(qualifiers, ", "))
+		case len(qualifiers) == 1 && page.Import == "":
+			return nil, nil, ctx.errorf(pageKey(pageName), "page %s: data type %q references package %q, but no import is given",
+				pageName, page.Data, qualifiers[0])
+		case len(qualifiers) == 0 && page.Import != "":
+			return nil, nil, ctx.errorf(pageKey(pageName), "page %s: import %q is not referenced by data type %q",
+				pageName, page.Import, page.Data)
+		}
+
+		if len(qualifiers) == 1 {
+			name := qualifiers[0]
+			if what, ok := reserved[name]; ok {
+				return nil, nil, ctx.errorf(pageKey(pageName), "page %s: package name %q conflicts with %s",
+					pageName, name, what)
+			}
+			for other := range ctx.Pages {
+				if name == ctx.pageConst(other) {
+					return nil, nil, ctx.errorf(pageKey(pageName), "page %s: package name %q conflicts with the constant of page %s",
+						pageName, name, other)
+				}
+			}
+			if path, ok := name2path[name]; ok && path != page.Import {
+				return nil, nil, ctx.errorf(pageKey(pageName), "page %s: package name %q refers to %q, but page %s uses it for %q",
+					pageName, name, page.Import, name2page[name], path)
+			}
+			name2path[name] = page.Import
+			name2page[name] = pageName
+		}
+
+		typed = append(typed, typedPage{Name: pageName, Data: page.Data})
+	}
+
+	imports := make([]importSpec, 0, len(name2path))
+	for name, path := range name2path {
+		imports = append(imports, importSpec{Name: name, Path: path})
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if imports[i].Path != imports[j].Path {
+			return imports[i].Path < imports[j].Path
+		}
+		return imports[i].Name < imports[j].Name
+	})
+
+	return typed, imports, nil
+}
+
+// parseDataType checks that s is a valid Go type expression.
+// It returns the (sorted) package names used as qualifiers in the type.
+func parseDataType(s string) ([]string, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data type %q: %s", s, err.Error())
+	}
+	var qualifiers []string
+	if !isTypeExpr(expr, &qualifiers) {
+		return nil, fmt.Errorf("invalid data type %q: not a type expression", s)
+	}
+	sort.Strings(qualifiers)
+
+	// remove duplicates
+	var res []string
+	for j, q := range qualifiers {
+		if j == 0 || q != qualifiers[j-1] {
+			res = append(res, q)
+		}
+	}
+	return res, nil
+}
+
+// isTypeExpr returns true if expr is a type expression.
+// The package names used as qualifiers are appended to qualifiers.
+func isTypeExpr(expr ast.Expr, qualifiers *[]string) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		*qualifiers = append(*qualifiers, x.Name)
+		return true
+	case *ast.ParenExpr:
+		return isTypeExpr(e.X, qualifiers)
+	case *ast.StarExpr:
+		return isTypeExpr(e.X, qualifiers)
+	case *ast.ArrayType:
+		if e.Len != nil {
+			if lit, ok := e.Len.(*ast.BasicLit); !ok || lit.Kind != token.INT {
+				return false
+			}
+		}
+		return isTypeExpr(e.Elt, qualifiers)
+	case *ast.MapType:
+		return isTypeExpr(e.Key, qualifiers) && isTypeExpr(e.Value, qualifiers)
+	case *ast.ChanType:
+		return isTypeExpr(e.Value, qualifiers)
+	case *ast.IndexExpr:
+		return isTypeExpr(e.X, qualifiers) && isTypeExpr(e.Index, qualifiers)
+	case *ast.IndexListExpr:
+		if !isTypeExpr(e.X, qualifiers) {
+			return false
+		}
+		for _, idx := range e.Indices {
+			if !isTypeExpr(idx, qualifiers) {
+				return false
+			}
+		}
+		return true
+	case *ast.InterfaceType, *ast.StructType, *ast.FuncType:
+		// inspect the package qualifiers of the fields types
+		ok := true
+		ast.Inspect(e, func(n ast.Node) bool {
+			if sel, isSel := n.(*ast.SelectorExpr); isSel {
+				ok = ok && isTypeExpr(sel, qualifiers)
+				return false
+			}
+			return true
+		})
+		return ok
+	}
+	return false
+}
+
+// checkImportPath checks that path is a syntactically valid import path.
+func checkImportPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("invalid import path %q: empty path", path)
+	}
+	for _, elem := range strings.Split(path, "/") {
+		switch elem {
+		case "":
+			return fmt.Errorf("invalid import path %q: empty path element", path)
+		case ".", "..":
+			return fmt.Errorf("invalid import path %q: invalid path element %q", path, elem)
+		}
+		for _, r := range elem {
+			switch {
+			case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			case strings.ContainsRune("-._~+", r):
+			default:
+				return fmt.Errorf("invalid import path %q: invalid char %q", path, r)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package run
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mmbros/gentmpl/run/types"
+)
+
+func TestParseDataType(t *testing.T) {
+	tests := []struct {
+		input      string
+		qualifiers []string
+		wantErr    bool
+	}{
+		{input: "string"},
+		{input: "*User"},
+		{input: "*models.User", qualifiers: []string{"models"}},
+		{input: "[]*models.User", qualifiers: []string{"models"}},
+		{input: "[3]models.User", qualifiers: []string{"models"}},
+		{input: "map[models.ID]other.User", qualifiers: []string{"models", "other"}},
+		{input: "map[string]any"},
+		{input: "struct{ U models.User; N int }", qualifiers: []string{"models"}},
+		{input: "Page[models.User]", qualifiers: []string{"models"}},
+		{input: "", wantErr: true},
+		{input: "*models.", wantErr: true},
+		{input: "1 + 2", wantErr: true},
+		{input: "f()", wantErr: true},
+		{input: "a.b.C", wantErr: true},
+		{input: "[n]int", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDataType(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDataType(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.qualifiers) {
+				t.Errorf("parseDataType(%q) = %v, want %v", tt.input, got, tt.qualifiers)
+			}
+		})
+	}
+}
+
+func TestCheckImportPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "example.com/app/models"},
+		{path: "github.com/pelletier/go-toml/v2"},
+		{path: "models"},
+		{path: "", wantErr: true},
+		{path: "/example.com/app", wantErr: true},
+		{path: "example.com/app/", wantErr: true},
+		{path: "example.com//app", wantErr: true},
+		{path: "example.com/../app", wantErr: true},
+		{path: "example.com/my app", wantErr: true},
+		{path: `example.com/"app"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := checkImportPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkImportPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPageDataTypesErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		pages       map[string]Page
+		goldenTests bool
+		errLike     string
+	}{
+		{
+			name:    "import without data",
+			pages:   map[string]Page{"P": {Template: "flat", Import: "example.com/models"}},
+			errLike: "without data type",
+		},
+		{
+			name:    "invalid data type",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "1+2"}},
+			errLike: "invalid data type",
+		},
+		{
+			name:    "missing import",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "*models.User"}},
+			errLike: "no import is given",
+		},
+		{
+			name:    "unused import",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "string", Import: "example.com/models"}},
+			errLike: "is not referenced",
+		},
+		{
+			name:    "invalid import",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "*models.User", Import: "example.com/my models"}},
+			errLike: "invalid import path",
+		},
+		{
+			name:    "many packages",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "map[a.K]b.V", Import: "example.com/a"}},
+			errLike: "more than one package",
+		},
+		{
+			name:    "reserved package name",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "*template.T", Import: "example.com/template"}},
+			errLike: "conflicts with the imports",
+		},
+		{
+			name:        "reserved package name of the golden tests",
+			pages:       map[string]Page{"P": {Template: "flat", Data: "json.RawMessage", Import: "encoding/json"}},
+			goldenTests: true,
+			errLike:     `package name "json" conflicts with the imports of the golden test file`,
+		},
+		{
+			name:    "generated identifier",
+			pages:   map[string]Page{"P": {Template: "flat", Data: "*routes.Route", Import: "example.com/routes"}},
+			errLike: `package name "routes" conflicts with an identifier of the generated package`,
+		},
+		{
+			name:    "page constant",
+			pages:   map[string]Page{"Page": {Template: "flat", Data: "*PagePage.T", Import: "example.com/PagePage"}},
+			errLike: `package name "PagePage" conflicts with the constant of page Page`,
+		},
+		{
+			name: "package name conflict",
+			pages: map[string]Page{
+				"P1": {Template: "flat", Data: "*models.User", Import: "example.com/a/models"},
+				"P2": {Template: "flat", Data: "*models.User", Import: "example.com/b/models"},
+			},
+			errLike: `package name "models" refers to`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &Context{Pages: tt.pages, Templates: templates, GoldenTests: tt.goldenTests, NoTemplateCheck: true}
+			err := ctx.Check()
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !errorLike(err, tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+		})
+	}
+}
+
+func TestReservedNames(t *testing.T) {
+	// every optional declaration of the generated package is enabled
+	ctx := &Context{
+		AssetManager:    types.AssetManagerEmbed,
+		FuncMap:         "funcs",
+		Init:            types.InitModeLazy,
+		BufferedExecute: true,
+		GoldenTests:     true,
+		TemplateKinds:   map[string]string{"inh1": "text"},
+		Pages: map[string]Page{
+			"Pag1": {Template: "flat", Base: "page-1", Route: "/", Data: "*models.User", Import: "example.com/models"},
+			"Inh1": {Template: "inh1"},
+		},
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	files, err := ctx.PackageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved := ctx.reservedNames()
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file.Name, file.Content, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					names = append(names, decl.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ImportSpec:
+						name, _ := strconv.Unquote(spec.Path.Value)
+						name = path.Base(name)
+						if spec.Name != nil {
+							name = spec.Name.Name
+						}
+						names = append(names, name)
+					case *ast.TypeSpec:
+						names = append(names, spec.Name.Name)
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							names = append(names, id.Name)
+						}
+					}
+				}
+			}
+		}
+		for _, name := range names {
+			if name == "models" || name == ctx.pageConst("Pag1") || name == ctx.pageConst("Inh1") {
+				continue
+			}
+			if _, ok := reserved[name]; !ok {
+				t.Errorf("%s: name %q declared by the generated code is not reserved", file.Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position is the position of a key in a configuration file.
+type Position struct {
+	File   string // path of the configuration file
+	Line   int    // line number, starting at 1
+	Column int    // column number, starting at 1
+}
+
+// IsValid returns true if the position has a line number.
+func (pos Position) IsValid() bool { return pos.Line > 0 }
+
+// String returns the position as "file:line:column", the format used by the
+// compilers and understood by the editors.
+// The line and column are omitted if the position is not valid.
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return pos.File
+	}
+	if pos.File == "" {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+// ConfigError is an error in the Context's parameters, at the position of
+// the configuration key that causes it.
+type ConfigError struct {
+	Pos Position
+	Msg string
+}
+
+// Error returns the message of the error, prefixed by its position if known
+// (ex: `gentmpl.conf:14:1: page Pag2: template "flat2" not defined`).
+func (e *ConfigError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+// Keys of the Positions map, given the name of a page, template or template
+// kind.
+func pageKey(name string) string         { return "pages." + name }
+func templateKey(name string) string     { return "templates." + name }
+func templateKindKey(name string) string { return "template_kinds." + name }
+
+// errorf returns a ConfigError at the position of the given key.
+func (ctx *Context) errorf(key string, format string, a ...any) error {
+	return &ConfigError{Pos: ctx.Positions[key], Msg: fmt.Sprintf(format, a...)}
+}
+
+// splitError returns the position and the message of err.
+func splitError(err error) (Position, string) {
+	var cerr *ConfigError
+	if errors.As(err, &cerr) {
+		return cerr.Pos, cerr.Msg
+	}
+	return Position{}, err.Error()
+}
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// pageRoute contains the information needed to register the handler of a
+// page in a http.ServeMux.
+type pageRoute struct {
+	Name    string // page name
+	Pattern string // http.ServeMux pattern (ex: "GET /users/{id}")
+}
+
+// pageRoutes checks the route and method attributes of the given pages.
+// It returns the pages with a route, and their http.ServeMux patterns.
+func (ctx *Context) pageRoutes(pageNames []string) ([]pageRoute, error) {
+	var routes []pageRoute
+
+	// the patterns are registered in a ServeMux to check their syntax and
+	// to find the conflicting ones
+	mux := http.NewServeMux()
+	// mapping from pattern to page name
+	pattern2page := map[string]string{}
+
+	for _, pageName := range pageNames {
+		page := ctx.Pages[pageName]
+
+		if page.Route == "" {
+			if page.Method != "" {
+				return nil, ctx.errorf(pageKey(pageName), "page %s: method %q without route", pageName, page.Method)
+			}
+			continue
+		}
+
+		pattern, err := routePattern(page.Method, page.Route)
+		if err != nil {
+			return nil, ctx.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+		}
+		if other, ok := pattern2page[pattern]; ok {
+			return nil, ctx.errorf(pageKey(pageName), "page %s: route %q already used by page %s", pageName, pattern, other)
+		}
+		if err := handlePattern(mux, pattern); err != nil {
+			return nil, ctx.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+		}
+		pattern2page[pattern] = pageName
+
+		routes = append(routes, pageRoute{Name: pageName, Pattern: pattern})
+	}
+	return routes, nil
+}
+
+// routePattern returns the http.ServeMux pattern of the route.
+// The method, if not empty, is prepended to the route, that must not
+// contain a method.
+func routePattern(method, route string) (string, error) {
+	routeMethod, rest, found := strings.Cut(route, " ")
+	if found && !strings.Contains(routeMethod, "/") {
+		if method != "" {
+			return "", fmt.Errorf("method %q given, but route %q already contains a method", method, route)
+		}
+		method, route = routeMethod, strings.TrimLeft(rest, " \t")
+	}
+	if method != "" {
+		if err := checkMethod(method); err != nil {
+			return "", err
+		}
+		return method + " " + route, nil
+	}
+	return route, nil
+}
+
+// checkMethod checks that method is an upper case HTTP method name.
+func checkMethod(method string) error {
+	for _, r := range method {
+		if r < 'A' || r > 'Z' {
+			return fmt.Errorf("invalid method %q: must contain only upper case letters", method)
+		}
+	}
+	return nil
+}
+
+// handlePattern registers the pattern in mux, returning the error that
+// causes ServeMux.Handle to panic, if any.
+func handlePattern(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid route %q: %v", pattern, r)
+		}
+	}()
+	mux.Handle(pattern, http.NotFoundHandler())
+	return nil
+}
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoutePattern(t *testing.T) {
+	tests := []struct {
+		method  string
+		route   string
+		want    string
+		wantErr bool
+	}{
+		{route: "/users/{id}", want: "/users/{id}"},
+		{route: "GET /users/{id}", want: "GET /users/{id}"},
+		{route: "GET   /users/{id}", want: "GET /users/{id}"},
+		{method: "POST", route: "/users", want: "POST /users"},
+		{route: "example.com/users", want: "example.com/users"},
+		{method: "get", route: "/users", wantErr: true},
+		{route: "get /users", wantErr: true},
+		{method: "POST", route: "GET /users", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
+			got, err := routePattern(tt.method, tt.route)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("routePattern(%q, %q) error = %v, wantErr %v", tt.method, tt.route, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("routePattern(%q, %q) = %q, want %q", tt.method, tt.route, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageRoutes(t *testing.T) {
+	ctx := &Context{
+		Pages: map[string]Page{
+			"Home":  {Template: "flat", Route: "/", Method: "GET"},
+			"User":  {Template: "flat", Route: "GET /users/{id}"},
+			"Other": {Template: "flat"},
+		},
+	}
+	got, err := ctx.pageRoutes([]string{"Home", "Other", "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pageRoute{
+		{Name: "Home", Pattern: "GET /"},
+		{Name: "User", Pattern: "GET /users/{id}"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pageRoutes: got %v, want %v", got, want)
+	}
+}
+
+func TestPageRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   map[string]Page
+		errLike string
+	}{
+		{
+			name:    "method without route",
+			pages:   map[string]Page{"P": {Template: "flat", Method: "GET"}},
+			errLike: "without route",
+		},
+		{
+			name:    "invalid method",
+			pages:   map[string]Page{"P": {Template: "flat", Route: "/p", Method: "G-T"}},
+			errLike: "invalid method",
+		},
+		{
+			name:    "method given twice",
+			pages:   map[string]Page{"P": {Template: "flat", Route: "GET /p", Method: "GET"}},
+			errLike: "already contains a method",
+		},
+		{
+			name:    "invalid route",
+			pages:   map[string]Page{"P": {Template: "flat", Route: "/users/{id"}},
+			errLike: "invalid route",
+		},
+		{
+			name: "duplicate route",
+			pages: map[string]Page{
+				"P1": {Template: "flat", Route: "GET /p"},
+				"P2": {Template: "flat", Route: "/p", Method: "GET"},
+			},
+			errLike: "already used by page P1",
+		},
+		{
+			name: "conflicting routes",
+			pages: map[string]Page{
+				"P1": {Template: "flat", Route: "/users/{id}/x"},
+				"P2": {Template: "flat", Route: "/users/new/{name}"},
+			},
+			errLike: "invalid route",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &Context{Pages: tt.pages, Templates: templates, NoTemplateCheck: true}
+			err := ctx.Check()
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !errorLike(err, tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+		})
+	}
+}
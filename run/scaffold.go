@@ -0,0 +1,289 @@
+package run
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// scaffoldExts are the extensions of the templates files read by Scaffold.
+var scaffoldExts = map[string]struct{}{
+	".gohtml": {},
+	".gotmpl": {},
+	".htm":    {},
+	".html":   {},
+	".tmpl":   {},
+	".tpl":    {},
+	".txt":    {},
+}
+
+// maxScaffoldVariants is the maximum number of template sets proposed for a
+// top-level template whose references are defined in more than one file.
+const maxScaffoldVariants = 8
+
+// scaffoldEntry is a top-level template: a non empty template that is not
+// referenced by any other template.
+type scaffoldEntry struct {
+	file string // file that defines the template
+	name string // name of the template
+}
+
+// Scaffold sets the Templates and Pages of the Context, proposing them from
+// the templates files found in the TemplateBaseDir folder and its
+// subfolders. The hidden files and folders are skipped.
+//
+// Each file is parsed to find the templates it defines with {{define}} and
+// the templates they reference with {{template}}. A page is proposed for
+// each top-level template, that is a non empty template not referenced by
+// any other template. The template of the page contains the file of the
+// top-level template, followed by the files needed to define every template
+// it references. If a referenced template is defined in more than one file,
+// a page is proposed for each of them (ex: a base layout with many contents).
+// The pages that need the same files share the same template.
+func (ctx *Context) Scaffold() error {
+	files, err := ctx.scanTemplateFiles()
+	if err != nil {
+		return err
+	}
+	root := nvl(ctx.TemplateBaseDir, ".")
+	if len(files) == 0 {
+		return fmt.Errorf("no templates files found in %s", root)
+	}
+
+	tf := &templateFiles{ctx: ctx}
+	// mapping from template name to the files that define it
+	definers := map[string][]string{}
+	referenced := map[string]struct{}{}
+	for _, file := range files {
+		f, err := tf.parse(file)
+		if err != nil {
+			return fmt.Errorf("file %s: %s", file, err.Error())
+		}
+		for _, name := range f.Defined {
+			definers[name] = append(definers[name], file)
+			for _, ref := range f.References[name] {
+				if ref != name {
+					referenced[ref] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var entries []scaffoldEntry
+	for _, file := range files {
+		f := tf.files[file]
+		empty := map[string]struct{}{}
+		for _, name := range f.Empty {
+			empty[name] = struct{}{}
+		}
+		for _, name := range f.Defined {
+			_, isReferenced := referenced[name]
+			_, isEmpty := empty[name]
+			if isReferenced || isEmpty {
+				continue
+			}
+			entries = append(entries, scaffoldEntry{file: file, name: name})
+		}
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no top-level templates found in %s", root)
+	}
+
+	ctx.Templates = map[string][]string{}
+	ctx.Pages = map[string]Page{}
+	// mapping from the files of a template to its name
+	setNames := map[string]string{}
+	for _, e := range entries {
+		sets := tf.scaffoldSets(e, definers)
+		for _, set := range sets {
+			key := strings.Join(set, "\n")
+			tmplName, ok := setNames[key]
+			if !ok {
+				tmplName = uniqueName(ctx.Templates, templateScaffoldName(set, sets))
+				ctx.Templates[tmplName] = set
+				setNames[key] = tmplName
+			}
+			page := Page{Template: tmplName}
+			if e.name != path.Base(e.file) {
+				page.Base = e.name
+			}
+			ctx.Pages[uniqueName(ctx.Pages, pageScaffoldName(e, set, sets))] = page
+		}
+	}
+	return nil
+}
+
+// scanTemplateFiles returns the (sorted) templates files found in the
+// TemplateBaseDir folder, relative to it.
+func (ctx *Context) scanTemplateFiles() ([]string, error) {
+	root := ctx.resolvePath(nvl(ctx.TemplateBaseDir, "."))
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := scaffoldExts[filepath.Ext(p)]; !ok || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// scaffoldSets returns the sets of files needed to execute the top-level
+// template e: the file of e, followed by the (sorted) files that define the
+// referenced templates. A set is returned for each choice of the file of the
+// templates defined in more than one file, up to maxScaffoldVariants.
+// The templates not defined in any file are ignored.
+func (tf *templateFiles) scaffoldSets(e scaffoldEntry, definers map[string][]string) [][]string {
+	// missing returns the first referenced template not defined in the
+	// files
+	missing := func(files []string) string {
+		visited := map[string]struct{}{}
+		var found string
+		var visit func(string)
+		visit = func(name string) {
+			if _, ok := visited[name]; ok || found != "" {
+				return
+			}
+			visited[name] = struct{}{}
+			defined := false
+			for _, file := range files {
+				refs, ok := tf.files[file].References[name]
+				if !ok {
+					continue
+				}
+				defined = true
+				for _, ref := range refs {
+					visit(ref)
+				}
+			}
+			if !defined && len(definers[name]) > 0 {
+				found = name
+			}
+		}
+		visit(e.name)
+		return found
+	}
+
+	var sets [][]string
+	var expand func([]string)
+	expand = func(files []string) {
+		if len(sets) >= maxScaffoldVariants {
+			return
+		}
+		name := missing(files)
+		if name == "" {
+			set := append([]string{files[0]}, files[1:]...)
+			sort.Strings(set[1:])
+			sets = append(sets, set)
+			return
+		}
+		for _, file := range definers[name] {
+			expand(append(files[:len(files):len(files)], file))
+		}
+	}
+	expand([]string{e.file})
+	return sets
+}
+
+// templateScaffoldName returns the name of the template with the given
+// files, based on the path of its first file and, if there are many
+// variants, on the files that are not in every variant.
+// Ex: "inheritance/base.tmpl" + "inheritance/content1.tmpl" ->
+// "inheritance_base_content1".
+func templateScaffoldName(set []string, variants [][]string) string {
+	parts := []string{strings.TrimSuffix(set[0], path.Ext(set[0]))}
+	for _, file := range variantFiles(set, variants) {
+		parts = append(parts, fileStem(file))
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, strings.Join(parts, "_"))
+}
+
+// pageScaffoldName returns the name of the page of the top-level template
+// e, executed with the given files: the name of the template, or the name
+// of its file, in camel case, followed by the files that are not in every
+// variant. Ex: "page-1" -> "Page1", "base.tmpl" + "content1.tmpl" ->
+// "BaseContent1".
+func pageScaffoldName(e scaffoldEntry, set []string, variants [][]string) string {
+	name := e.name
+	if name == path.Base(e.file) {
+		name = fileStem(e.file)
+	}
+	parts := []string{name}
+	for _, file := range variantFiles(set, variants) {
+		parts = append(parts, fileStem(file))
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		words := strings.FieldsFunc(part, func(r rune) bool {
+			return r >= unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	if b.Len() == 0 {
+		return "Page"
+	}
+	return b.String()
+}
+
+// variantFiles returns the files of set that are not in every variant.
+func variantFiles(set []string, variants [][]string) []string {
+	if len(variants) < 2 {
+		return nil
+	}
+	count := map[string]int{}
+	for _, v := range variants {
+		for _, file := range v {
+			count[file]++
+		}
+	}
+	var files []string
+	for _, file := range set {
+		if count[file] < len(variants) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// fileStem returns the base name of the file without the extension.
+func fileStem(file string) string {
+	base := path.Base(file)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// uniqueName returns name, followed by a number if needed to be not used as
+// key of m.
+func uniqueName[V any](m map[string]V, name string) string {
+	unique := name
+	for j := 2; ; j++ {
+		if _, ok := m[unique]; !ok {
+			return unique
+		}
+		unique = fmt.Sprintf("%s%d", name, j)
+	}
+}
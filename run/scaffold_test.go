@@ -0,0 +1,121 @@
+package run
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &Context{TemplateBaseDir: templateBaseDir, Dir: dir}
+	if err := ctx.Scaffold(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantTemplates := map[string][]string{
+		"flat_page1":                {"flat/page1.tmpl", "flat/footer.tmpl", "flat/header.tmpl"},
+		"flat_page2and3":            {"flat/page2and3.tmpl", "flat/footer.tmpl", "flat/header.tmpl"},
+		"inheritance_base_content1": {"inheritance/base.tmpl", "inheritance/content1.tmpl"},
+		"inheritance_base_content2": {"inheritance/base.tmpl", "inheritance/content2.tmpl"},
+	}
+	wantPages := map[string]Page{
+		"BaseContent1": {Template: "inheritance_base_content1"},
+		"BaseContent2": {Template: "inheritance_base_content2"},
+		"Page1":        {Template: "flat_page1", Base: "page-1"},
+		"Page2":        {Template: "flat_page2and3", Base: "page-2"},
+		"Page3":        {Template: "flat_page2and3", Base: "page-3"},
+	}
+	if !reflect.DeepEqual(ctx.Templates, wantTemplates) {
+		t.Errorf("Templates:\ngot  %v\nwant %v", ctx.Templates, wantTemplates)
+	}
+	if !reflect.DeepEqual(ctx.Pages, wantPages) {
+		t.Errorf("Pages:\ngot  %v\nwant %v", ctx.Pages, wantPages)
+	}
+	if diags := ctx.Validate(); len(diags) > 0 {
+		t.Errorf("Validate() = %v, want no diagnostics", diags)
+	}
+}
+
+func TestScaffoldFiles(t *testing.T) {
+	files := map[string]string{
+		"layout.html":        `<body>{{template "nav" .}}{{template "main" .}}</body>`,
+		"nav.html":           `{{define "nav"}}{{template "item" .}}{{template "nav" .Sub}}{{end}}`,
+		"home.html":          `{{define "main"}}home{{end}}`,
+		"mail/welcome.txt":   `{{define "welcome-mail"}}Hello {{template "signature"}}{{end}}`,
+		"mail/sign.txt":      `{{define "signature"}}bye{{end}}`,
+		"mail/sign-old.txt":  `{{define "signature"}}bye bye{{end}}`,
+		"static/style.css":   `body { color: red }`,
+		".cache/draft.html":  `{{define "draft"}}{{end}}`,
+		"partials/.hidden.t": `{{define "x"}}`,
+	}
+	dir := t.TempDir()
+	for path, content := range files {
+		if err := writeFile(filepath.Join(dir, templateBaseDir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &Context{TemplateBaseDir: templateBaseDir, Dir: dir}
+	if err := ctx.Scaffold(); err != nil {
+		t.Fatal(err)
+	}
+
+	// "item" is not defined in any file, and "nav" references itself
+	wantTemplates := map[string][]string{
+		"layout":                {"layout.html", "home.html", "nav.html"},
+		"mail_welcome_sign":     {"mail/welcome.txt", "mail/sign.txt"},
+		"mail_welcome_sign-old": {"mail/welcome.txt", "mail/sign-old.txt"},
+	}
+	wantPages := map[string]Page{
+		"Layout":             {Template: "layout"},
+		"WelcomeMailSign":    {Template: "mail_welcome_sign", Base: "welcome-mail"},
+		"WelcomeMailSignOld": {Template: "mail_welcome_sign-old", Base: "welcome-mail"},
+	}
+	if !reflect.DeepEqual(ctx.Templates, wantTemplates) {
+		t.Errorf("Templates:\ngot  %v\nwant %v", ctx.Templates, wantTemplates)
+	}
+	if !reflect.DeepEqual(ctx.Pages, wantPages) {
+		t.Errorf("Pages:\ngot  %v\nwant %v", ctx.Pages, wantPages)
+	}
+}
+
+func TestScaffoldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		errLike string
+	}{
+		{
+			name:    "no files",
+			files:   map[string]string{"style.css": "body {}"},
+			errLike: "no templates files found in " + templateBaseDir,
+		},
+		{
+			name:    "no top-level templates",
+			files:   map[string]string{"a.tmpl": `{{define "a"}}{{template "b"}}{{end}}{{define "b"}}{{template "a"}}{{end}}`},
+			errLike: "no top-level templates found in " + templateBaseDir,
+		},
+		{
+			name:    "parse error",
+			files:   map[string]string{"a.tmpl": `{{define "a"}}`},
+			errLike: "file a.tmpl: template: a.tmpl:1: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for path, content := range tt.files {
+				if err := writeFile(filepath.Join(dir, templateBaseDir, path), content); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ctx := &Context{TemplateBaseDir: templateBaseDir, Dir: dir}
+			if err := ctx.Scaffold(); !errorLike(err, tt.errLike) {
+				t.Errorf("Scaffold() error = %v, want error like %q", err, tt.errLike)
+			}
+		})
+	}
+}
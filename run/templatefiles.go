@@ -0,0 +1,335 @@
+package run
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run/collection"
+	"github.com/mmbros/gentmpl/run/lib"
+)
+
+// resolvePath returns the path used to access the given path at generation
+// time, taking into account the Dir parameter.
+func (ctx *Context) resolvePath(path string) string {
+	if ctx.Dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.Dir, path)
+	}
+	return path
+}
+
+// filePath returns the path of the file used by the generated package.
+// The path is computed as in the file2path func of the generated package.
+func (ctx *Context) filePath(file string) string {
+	switch {
+	case len(file) == 0, file[0] == '.', file[0] == filepath.Separator:
+		return file
+	default:
+		return filepath.Join(ctx.TemplateBaseDir, file)
+	}
+}
+
+// templateFilePath returns the path used to read the file at generation time.
+func (ctx *Context) templateFilePath(file string) string {
+	return ctx.resolvePath(ctx.filePath(file))
+}
+
+// embedPathError returns an error if the file cannot be read from the
+// embed.FS of the generated package: the path must be inside the package
+// folder, and must not start with "./" since the generated package does not
+// join it with the template_base_dir folder.
+func (ctx *Context) embedPathError(file string) error {
+	path := filepath.ToSlash(ctx.filePath(file))
+	switch {
+	case fs.ValidPath(path):
+		return nil
+	case filepath.IsAbs(path), path == "..", strings.HasPrefix(path, "../"):
+		return fmt.Errorf("%q is outside the package folder", path)
+	default:
+		return fmt.Errorf("%q is not a valid embed path", path)
+	}
+}
+
+// glob returns the files matching the pattern.
+// As the items of the templates, the pattern and the returned files are
+// relative to the template_base_dir folder, unless they start with '.' or
+// the path separator.
+func (ctx *Context) glob(pattern string) ([]string, error) {
+	// split the pattern in a prefix without meta characters and the rest
+	elems := strings.Split(pattern, "/")
+	i := 0
+	for i < len(elems)-1 && !lib.IsGlob(elems[i]) {
+		i++
+	}
+	prefix := strings.Join(elems[:i], "/")
+
+	var root string
+	if prefix == "" {
+		root = ctx.resolvePath(ctx.TemplateBaseDir)
+	} else {
+		root = ctx.templateFilePath(prefix)
+	}
+	if root == "" {
+		root = "."
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	files, err := lib.Glob(os.DirFS(root), strings.Join(elems[i:], "/"))
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		for j, file := range files {
+			files[j] = prefix + "/" + file
+		}
+	}
+	return files, nil
+}
+
+// resolveTemplates returns the mapping from each given template name to the
+// files of the template, resolving the included templates and expanding the
+// glob patterns.
+func (ctx *Context) resolveTemplates(names []string) (map[string][]string, error) {
+	// resolve the templates one at a time, to report the template of an
+	// include cycle
+	for _, tmplName := range names {
+		if _, err := lib.ResolveIncludes(ctx.Templates, []string{tmplName}); err != nil {
+			return nil, ctx.errorf(templateKey(tmplName), "template %s: %s", tmplName, err.Error())
+		}
+	}
+	t2af, err := lib.ResolveIncludes(ctx.Templates, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// expand the glob patterns
+	for _, tmplName := range names {
+		files, err := lib.ExpandGlobs(t2af[tmplName], ctx.glob)
+		if err != nil {
+			return nil, ctx.errorf(templateKey(tmplName), "template %s: %s", tmplName, err.Error())
+		}
+		t2af[tmplName] = files
+	}
+	return t2af, nil
+}
+
+// TemplateFiles returns the (sorted) paths of the templates files used by the
+// pages, as accessed at generation time.
+// Unlike Check, it does not read the files and it ignores the pages whose
+// template is not defined.
+func (ctx *Context) TemplateFiles() ([]string, error) {
+	names := collection.NewUniqueStrings()
+	for _, page := range ctx.Pages {
+		if _, ok := ctx.Templates[page.Template]; ok {
+			names.Add(page.Template)
+		}
+	}
+	names.Sort()
+
+	t2af, err := ctx.resolveTemplates(names.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
+	paths := collection.NewUniqueStrings()
+	for _, name := range names.ToSlice() {
+		for _, file := range t2af[name] {
+			paths.Add(ctx.templateFilePath(file))
+		}
+	}
+	paths.Sort()
+	return paths.ToSlice(), nil
+}
+
+// templateFiles reads and parses the templates files.
+// Each file is parsed once, even if used by many templates.
+type templateFiles struct {
+	ctx   *Context
+	files map[string]*lib.TemplateFile
+}
+
+// parse returns the parsed file.
+func (tf *templateFiles) parse(file string) (*lib.TemplateFile, error) {
+	if f, ok := tf.files[file]; ok {
+		return f, nil
+	}
+	path := tf.ctx.templateFilePath(file)
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := lib.ParseTemplateFile(filepath.Base(file), string(buf))
+	if err != nil {
+		return nil, err
+	}
+	if tf.files == nil {
+		tf.files = map[string]*lib.TemplateFile{}
+	}
+	tf.files[file] = f
+	return f, nil
+}
+
+// templateSet contains the information about the templates defined and
+// referenced by the files of a template.
+type templateSet struct {
+	// names of the defined templates
+	defined map[string]struct{}
+	// mapping from template name to the names of the referenced templates
+	references map[string][]string
+	// mapping from referenced template name to the files that reference it
+	referencedBy map[string][]string
+}
+
+// templateSet returns the information about the templates defined and
+// referenced by the files.
+func (tf *templateFiles) templateSet(files []string) (*templateSet, error) {
+	ts := &templateSet{
+		defined:      map[string]struct{}{},
+		references:   map[string][]string{},
+		referencedBy: map[string][]string{},
+	}
+	for _, file := range files {
+		f, err := tf.parse(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range f.Defined {
+			ts.defined[name] = struct{}{}
+			refs := f.References[name]
+			ts.references[name] = append(ts.references[name], refs...)
+			for _, ref := range refs {
+				by := ts.referencedBy[ref]
+				if len(by) == 0 || by[len(by)-1] != file {
+					ts.referencedBy[ref] = append(by, file)
+				}
+			}
+		}
+	}
+	return ts, nil
+}
+
+// undefined returns the (sorted) names of the referenced templates that are
+// not defined.
+func (ts *templateSet) undefined() []string {
+	var names []string
+	for ref := range ts.referencedBy {
+		if _, ok := ts.defined[ref]; !ok {
+			names = append(names, ref)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reachableUndefined returns the (sorted) names of the undefined templates
+// that can be reached executing the entry template.
+func (ts *templateSet) reachableUndefined(entry string) []string {
+	var names []string
+	visited := map[string]struct{}{}
+
+	var visit func(string)
+	visit = func(name string) {
+		if _, ok := visited[name]; ok {
+			return
+		}
+		visited[name] = struct{}{}
+		if _, ok := ts.defined[name]; !ok {
+			names = append(names, name)
+			return
+		}
+		for _, ref := range ts.references[name] {
+			visit(ref)
+		}
+	}
+	visit(entry)
+
+	sort.Strings(names)
+	return names
+}
+
+// templateFilesErrors reads and parses the files of the templates used by
+// the pages. It checks that:
+//   - the base of each page is defined in its template;
+//   - every template referenced with {{template "name"}} is defined in the
+//     files of the template.
+//
+// t2af is the mapping from template name to the resolved files.
+// It returns every error found: an error for each template whose files
+// cannot be parsed, an error for each page whose base is not defined, and
+// an error reporting all the undefined templates referenced with
+// {{template}}.
+func (ctx *Context) templateFilesErrors(templateNames, pageNames []string, t2af map[string][]string) []error {
+	tf := &templateFiles{ctx: ctx}
+	var errs []error
+
+	sets := map[string]*templateSet{}
+	for _, tmplName := range templateNames {
+		ts, err := tf.templateSet(t2af[tmplName])
+		if err != nil {
+			errs = append(errs, ctx.errorf(templateKey(tmplName), "template %s: %s", tmplName, err.Error()))
+			continue
+		}
+		sets[tmplName] = ts
+	}
+
+	// check the bases
+	for _, pageName := range pageNames {
+		page := ctx.Pages[pageName]
+		ts, ok := sets[page.Template]
+		if page.Base == "" || !ok {
+			continue
+		}
+		if _, ok := ts.defined[page.Base]; !ok {
+			errs = append(errs, ctx.errorf(pageKey(pageName), "page %s: base %q not defined in template %s (files: %s)",
+				pageName, page.Base, page.Template, strings.Join(t2af[page.Template], ", ")))
+		}
+	}
+
+	// check the references
+	var b strings.Builder
+	for _, tmplName := range templateNames {
+		ts, ok := sets[tmplName]
+		if !ok {
+			continue
+		}
+		undefined := ts.undefined()
+		if len(undefined) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  template %s:", tmplName)
+		for _, name := range undefined {
+			fmt.Fprintf(&b, "\n    %q referenced in %s", name, strings.Join(ts.referencedBy[name], ", "))
+		}
+	}
+	if b.Len() == 0 {
+		return errs
+	}
+	for _, pageName := range pageNames {
+		page := ctx.Pages[pageName]
+		ts, ok := sets[page.Template]
+		if !ok {
+			continue
+		}
+		entry := page.Base
+		if entry == "" {
+			files := t2af[page.Template]
+			if len(files) == 0 {
+				continue
+			}
+			// the template executed by template.Execute
+			entry = filepath.Base(files[0])
+		}
+		undefined := ts.reachableUndefined(entry)
+		if len(undefined) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  page %s (template %s): %s", pageName, page.Template, astr2str(undefined))
+	}
+
+	return append(errs, fmt.Errorf("undefined templates referenced with {{template}}:%s", b.String()))
+}
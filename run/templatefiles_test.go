@@ -0,0 +1,250 @@
+package run
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pages   map[string]Page
+		errLike string
+	}{
+		{
+			name:  "ok",
+			pages: pages,
+		},
+		{
+			name: "base not defined",
+			pages: map[string]Page{
+				"Pag1": {Template: "flat", Base: "page-1"},
+				"Pag2": {Template: "flat", Base: "page2"},
+			},
+			errLike: `page Pag2: base "page2" not defined in template flat (files: flat/footer.tmpl, flat/header.tmpl, flat/page1.tmpl, flat/page2and3.tmpl)`,
+		},
+		{
+			name: "base is a file name",
+			pages: map[string]Page{
+				"Inh1": {Template: "inh1", Base: "base.tmpl"},
+			},
+		},
+		{
+			name: "missing file",
+			pages: map[string]Page{
+				"Miss": {Template: "missing"},
+			},
+			errLike: "file flat/missing.tmpl not found (templates: missing)",
+		},
+	}
+	runCheckTemplateFilesTests(t, dir, tests)
+}
+
+func TestCheckTemplateReferences(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pages   map[string]Page
+		errLike string
+	}{
+		{
+			name: "missing partial",
+			pages: map[string]Page{
+				"Pag1": {Template: "noheader", Base: "page-1"},
+				"Pag3": {Template: "noheader", Base: "page-3"},
+				"Inh1": {Template: "nocontent"},
+				"Inh2": {Template: "inh2"},
+			},
+			errLike: `undefined templates referenced with {{template}}:
+  template nocontent:
+    "content" referenced in inheritance/base.tmpl
+  template noheader:
+    "header" referenced in flat/page1.tmpl, flat/page2and3.tmpl
+  page Inh1 (template nocontent): "content"
+  page Pag1 (template noheader): "header"
+  page Pag3 (template noheader): "header"`,
+		},
+		{
+			name: "unreachable reference",
+			pages: map[string]Page{
+				"Pag1": {Template: "noheader", Base: "footer"},
+			},
+			errLike: `undefined templates referenced with {{template}}:
+  template noheader:
+    "header" referenced in flat/page1.tmpl, flat/page2and3.tmpl`,
+		},
+	}
+	runCheckTemplateFilesTests(t, dir, tests)
+}
+
+func runCheckTemplateFilesTests(t *testing.T, dir string, tests []struct {
+	name    string
+	pages   map[string]Page
+	errLike string
+}) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := map[string][]string{
+				"missing":   {"flat/missing.tmpl"},
+				"noheader":  {"flat/footer.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl"},
+				"nocontent": {"inheritance/base.tmpl"},
+			}
+			for k, v := range templates {
+				tmpl[k] = v
+			}
+			ctx := &Context{
+				Pages:           tt.pages,
+				Templates:       tmpl,
+				TemplateBaseDir: templateBaseDir,
+				Dir:             dir,
+			}
+			err := ctx.Check()
+			if tt.errLike == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err.Error())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error like %q; no error found", tt.errLike)
+			}
+			if !errorLike(err, tt.errLike) {
+				t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+			}
+
+			// the check can be disabled
+			ctx.NoTemplateCheck = true
+			if err := ctx.Check(); err != nil {
+				t.Errorf("no_template_check: unexpected error: %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestGlobTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		baseDir string
+		items   []string
+		want    []string
+		errLike string
+	}{
+		{
+			name:    "base dir",
+			dir:     dir,
+			baseDir: templateBaseDir,
+			items:   []string{"inheritance/base.tmpl", "flat/*.tmpl"},
+			want:    []string{"inheritance/base.tmpl", "flat/footer.tmpl", "flat/header.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl"},
+		},
+		{
+			name:    "double star",
+			dir:     dir,
+			baseDir: templateBaseDir,
+			items:   []string{"**/content*.tmpl"},
+			want:    []string{"inheritance/content1.tmpl", "inheritance/content2.tmpl"},
+		},
+		{
+			name:  "dot prefix",
+			dir:   dir,
+			items: []string{"./tmpl/inheritance/*.tmpl"},
+			want:  []string{"./tmpl/inheritance/base.tmpl", "./tmpl/inheritance/content1.tmpl", "./tmpl/inheritance/content2.tmpl"},
+		},
+		{
+			name:    "absolute path",
+			baseDir: templateBaseDir,
+			items:   []string{filepath.Join(dir, templateBaseDir, "flat") + "/page*.tmpl"},
+			want: []string{
+				filepath.Join(dir, templateBaseDir, "flat", "page1.tmpl"),
+				filepath.Join(dir, templateBaseDir, "flat", "page2and3.tmpl"),
+			},
+		},
+		{
+			name:    "no match",
+			dir:     dir,
+			baseDir: templateBaseDir,
+			items:   []string{"flat/*.html"},
+			errLike: `template t: pattern "flat/*.html" matches no files`,
+		},
+		{
+			name:    "missing folder",
+			dir:     dir,
+			baseDir: templateBaseDir,
+			items:   []string{"missing/*.tmpl"},
+			errLike: `template t: pattern "missing/*.tmpl" matches no files`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &Context{
+				Pages:           map[string]Page{"P": {Template: "t"}},
+				Templates:       map[string][]string{"t": tt.items},
+				TemplateBaseDir: tt.baseDir,
+				Dir:             tt.dir,
+				NoTemplateCheck: true,
+			}
+			data, err := ctx.checkAndPrepare()
+			if tt.errLike != "" {
+				if err == nil {
+					t.Fatalf("expected error like %q; no error found", tt.errLike)
+				}
+				if !errorLike(err, tt.errLike) {
+					t.Errorf("expected error like %q; found error %q", tt.errLike, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(data.Files, tt.want) {
+				t.Errorf("Files = %v, want %v", data.Files, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &Context{
+		Pages: map[string]Page{
+			"Inh1": {Template: "inh1"},
+			"Pag1": {Template: "flat", Base: "page-1"},
+			"Miss": {Template: "missing"},
+		},
+		Templates:       templatesGlob,
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+	}
+	got, err := ctx.TemplateFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, file := range []string{
+		"flat/footer.tmpl", "flat/header.tmpl", "flat/page1.tmpl", "flat/page2and3.tmpl",
+		"inheritance/base.tmpl", "inheritance/content1.tmpl",
+	} {
+		want = append(want, filepath.Join(dir, templateBaseDir, file))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateFiles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package run
+
+import "sort"
+
+// template kinds
+const (
+	kindHTML = "html" // html/template
+	kindText = "text" // text/template
+)
+
+// templateKinds checks the TemplateKinds parameter.
+// It returns, for each of the given templates, true if the template uses
+// text/template and false if it uses html/template.
+// The templates without a kind use text/template if TextTemplate is true.
+func (ctx *Context) templateKinds(templateNames []string) ([]bool, error) {
+	// check the kinds in a deterministic order
+	names := make([]string, 0, len(ctx.TemplateKinds))
+	for name := range ctx.TemplateKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ctx.checkTemplateKind(name); err != nil {
+			return nil, err
+		}
+	}
+
+	ti2text := make([]bool, len(templateNames))
+	for tmplIdx, tmplName := range templateNames {
+		switch ctx.TemplateKinds[tmplName] {
+		case kindHTML:
+			ti2text[tmplIdx] = false
+		case kindText:
+			ti2text[tmplIdx] = true
+		default:
+			ti2text[tmplIdx] = ctx.TextTemplate
+		}
+	}
+	return ti2text, nil
+}
+
+// checkTemplateKind checks the kind of the given template, defined in the
+// TemplateKinds parameter.
+func (ctx *Context) checkTemplateKind(name string) error {
+	if _, ok := ctx.Templates[name]; !ok {
+		return ctx.errorf(templateKindKey(name), "template kind of undefined template %q", name)
+	}
+	switch kind := ctx.TemplateKinds[name]; kind {
+	case kindHTML, kindText:
+		return nil
+	default:
+		return ctx.errorf(templateKindKey(name), "template %s: invalid kind %q: must be %q or %q", name, kind, kindHTML, kindText)
+	}
+}
+
+// isMixed returns true if some templates use html/template and others use
+// text/template.
+func isMixed(ti2text []bool) bool {
+	for _, text := range ti2text {
+		if text != ti2text[0] {
+			return true
+		}
+	}
+	return false
+}
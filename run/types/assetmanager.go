@@ -80,6 +80,14 @@ func (am *AssetManager) UnmarshalText(data []byte) (err error) {
 	return
 }
 
+// MarshalText implements the encoding.TextMarshaler interface.
+func (am AssetManager) MarshalText() ([]byte, error) {
+	if am >= AssetManager(len(reprAssetManager)) {
+		return nil, fmt.Errorf("invalid asset manager: %d", am)
+	}
+	return []byte(am.String()), nil
+}
+
 // MarshalTOML implements the toml.Marshaler interface.
 func (am AssetManager) MarshalTOML() ([]byte, error) {
 	// ref: https://godoc.org/github.com/naoina/toml#Marshaler
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InitMode is the enum type of the template initialization modes
+type InitMode uint8
+
+// InitMode possible values
+const (
+	InitModeEager InitMode = iota // all the templates are created at once
+	InitModeLazy                  // each template is created on first use
+)
+
+// string representation of InitMode
+var reprInitMode = [...]string{"eager", "lazy"}
+
+// IsEager returns true if InitMode is Eager
+func (im InitMode) IsEager() bool { return im == InitModeEager }
+
+// IsLazy returns true if InitMode is Lazy
+func (im InitMode) IsLazy() bool { return im == InitModeLazy }
+
+// ParseInitMode converts a string to an InitMode value.
+func ParseInitMode(s string) (InitMode, error) {
+	switch strings.ToLower(s) {
+	case "eager", "":
+		return InitModeEager, nil
+	case "lazy":
+		return InitModeLazy, nil
+	}
+	return InitModeEager, fmt.Errorf("invalid init mode: %q", s)
+}
+
+// String return the string representation of an InitMode value.
+func (im InitMode) String() string {
+	if im >= InitMode(len(reprInitMode)) {
+		return fmt.Sprint("InitMode(", int(im), ")")
+	}
+	return reprInitMode[im]
+}
+
+// Set function for implementing flag.Value interface.
+func (im *InitMode) Set(s string) error {
+	u, err := ParseInitMode(s)
+	if err != nil {
+		return err
+	}
+	*im = u
+	return nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (im *InitMode) UnmarshalText(data []byte) (err error) {
+	*im, err = ParseInitMode(string(data))
+	return
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (im InitMode) MarshalText() ([]byte, error) {
+	if im >= InitMode(len(reprInitMode)) {
+		return nil, fmt.Errorf("invalid init mode: %d", im)
+	}
+	return []byte(im.String()), nil
+}
+
+// MarshalTOML implements the toml.Marshaler interface.
+func (im InitMode) MarshalTOML() ([]byte, error) {
+	if im >= InitMode(len(reprInitMode)) {
+		return nil, fmt.Errorf("invalid init mode: %d", im)
+	}
+	s := fmt.Sprintf("%q", im.String())
+	return []byte(s), nil
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/naoina/toml"
+)
+
+func TestInitModeString(t *testing.T) {
+	var testCases = []struct {
+		initMode InitMode
+		expected string
+	}{
+		{InitModeEager, "eager"},
+		{InitModeLazy, "lazy"},
+		{100, "InitMode(100)"},
+	}
+
+	for _, tc := range testCases {
+		actual := tc.initMode.String()
+		if actual != tc.expected {
+			t.Errorf("InitMode %d: expected %q, found %q", tc.initMode, tc.expected, actual)
+		}
+	}
+}
+
+func TestInitModeUnmarshal(t *testing.T) {
+	var testCases = []struct {
+		input    string
+		expected InitMode
+		ok       bool
+	}{
+		{"NO_DECL", InitModeEager, true},
+		{"", InitModeEager, true},
+		{"Eager", InitModeEager, true},
+		{"lazy", InitModeLazy, true},
+		{"LAZY", InitModeLazy, true},
+		{"later", InitModeEager, false},
+	}
+
+	type config struct {
+		Init InitMode
+	}
+
+	for i, tc := range testCases {
+		var cfg config
+		var text string
+		// case 0: test toml without init declaration
+		if i > 0 {
+			text = fmt.Sprintf("init = %q", tc.input)
+		}
+
+		err := toml.Unmarshal([]byte(text), &cfg)
+		if tc.ok {
+			if err != nil {
+				t.Errorf("Unexpected error for input %q: %s", tc.input, err.Error())
+			} else if cfg.Init != tc.expected {
+				t.Errorf("Input %q: expected %q, found %q", tc.input, tc.expected, cfg.Init)
+			}
+		} else {
+			if err == nil {
+				t.Errorf("Expected error for input %q: found %[2]d (%[2]s)", tc.input, cfg.Init)
+			}
+		}
+	}
+}
+
+func TestInitModeMarshal(t *testing.T) {
+	var testCases = []struct {
+		input    InitMode
+		expected string
+		ok       bool
+	}{
+		{InitModeEager, "eager", true},
+		{InitModeLazy, "lazy", true},
+		{InitMode(100), "", false},
+	}
+
+	type config struct {
+		Init InitMode
+	}
+
+	for _, tc := range testCases {
+		b, err := toml.Marshal(config{Init: tc.input})
+		if tc.ok {
+			if err != nil {
+				t.Errorf("Marshal(%s) unexpected error: %s", tc.input.String(), err.Error())
+				continue
+			}
+			actual := string(b)
+			expect := fmt.Sprintf("init = %q\n", tc.expected)
+			if actual != expect {
+				t.Errorf("Marshal(%s): expected %q, got %q", tc.input.String(), expect, actual)
+			}
+		} else if err == nil {
+			t.Errorf("Marshal(%s) expected error not raised", tc.input.String())
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package run
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mmbros/gentmpl/run/collection"
+	"github.com/mmbros/gentmpl/run/types"
+)
+
+// Severity is the severity level of a Diagnostic.
+type Severity string
+
+// Severity values
+const (
+	// SeverityError is the severity of the problems that prevent the
+	// generation of the package.
+	SeverityError Severity = "error"
+	// SeverityWarning is the severity of the problems that do not prevent
+	// the generation of the package.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in the Context's parameters.
+type Diagnostic struct {
+	Severity Severity
+	Pos      Position // position of the configuration key, if known
+	Message  string
+}
+
+// String returns the diagnostic as "file:line:column: message", or
+// "file:line:column: warning: message" for the warnings.
+// The position is omitted if not known.
+func (d Diagnostic) String() string {
+	s := d.Message
+	if d.Severity == SeverityWarning {
+		s = "warning: " + s
+	}
+	if d.Pos.IsValid() {
+		s = d.Pos.String() + ": " + s
+	}
+	return s
+}
+
+// validator collects the diagnostics of a Context.
+type validator struct {
+	ctx   *Context
+	diags []Diagnostic
+}
+
+// errorf adds an error at the position of the given key.
+func (v *validator) errorf(key string, format string, a ...any) {
+	v.add(SeverityError, v.ctx.errorf(key, format, a...))
+}
+
+// warningf adds a warning at the position of the given key.
+func (v *validator) warningf(key string, format string, a ...any) {
+	v.add(SeverityWarning, v.ctx.errorf(key, format, a...))
+}
+
+// add adds a diagnostic with the position and the message of err.
+func (v *validator) add(severity Severity, err error) {
+	pos, msg := splitError(err)
+	v.diags = append(v.diags, Diagnostic{Severity: severity, Pos: pos, Message: msg})
+}
+
+// Validate checks the Context's parameters. Unlike Check, that returns only
+// the first error, it returns every problem found, including the warnings
+// that do not prevent the generation of the package.
+// The package can be generated if no diagnostic has SeverityError.
+func (ctx *Context) Validate() []Diagnostic {
+	v := &validator{ctx: ctx}
+
+	v.checkParams()
+	pageNames, templateNames := v.checkPages()
+	t2af := v.checkTemplates(templateNames)
+	v.checkTemplateKinds()
+	v.checkPageAttributes(pageNames)
+
+	if !ctx.NoTemplateCheck && len(t2af) > 0 {
+		// check the files of the templates that are resolved and the pages
+		// that use them
+		var tmpls, pages []string
+		for _, name := range templateNames {
+			if _, ok := t2af[name]; ok {
+				tmpls = append(tmpls, name)
+			}
+		}
+		for _, name := range pageNames {
+			if _, ok := t2af[ctx.Pages[name].Template]; ok {
+				pages = append(pages, name)
+			}
+		}
+		for _, err := range ctx.templateFilesErrors(tmpls, pages, t2af) {
+			v.add(SeverityError, err)
+		}
+	}
+
+	return v.diags
+}
+
+// checkParams checks the parameters that are not templates or pages.
+func (v *validator) checkParams() {
+	ctx := v.ctx
+
+	switch ctx.AssetManager {
+	case types.AssetManagerNone, types.AssetManagerEmbed:
+		// ok
+	default:
+		v.errorf("asset_manager", "assetManager not supported: %q", ctx.AssetManager)
+	}
+
+	idents := []struct {
+		param, value string
+	}{
+		{"package_name", nvl(ctx.PackageName, defaultPackageName)},
+		{"page_enum_type", nvl(ctx.PageEnumType, defaultPageEnumType)},
+		{"template_enum_type", nvl(ctx.TemplateEnumType, defaultTemplateEnumType)},
+		{"func_map", ctx.FuncMap},
+	}
+	for _, id := range idents {
+		if id.value != "" && !token.IsIdentifier(id.value) {
+			v.errorf(id.param, "%s: %q is not a valid Go identifier", id.param, id.value)
+		}
+	}
+
+	if token.IsIdentifier(ctx.FuncMap) {
+		if err := ctx.checkFuncMap(); err != nil {
+			v.add(SeverityError, err)
+		}
+	}
+
+	if _, err := ctx.timestamp(); err != nil {
+		v.add(SeverityError, err)
+	}
+
+	if ctx.Init.IsLazy() && ctx.NoCache {
+		v.warningf("init", "init %q is ignored with no_cache", ctx.Init)
+	}
+}
+
+// pageConst returns the PageEnum constant of the page with given name.
+func (ctx *Context) pageConst(pageName string) string {
+	return nvl(ctx.PageEnumPrefix, defaultPagePrefix) + pageName + ctx.PageEnumSuffix
+}
+
+// checkPageConst returns an error if the PageEnum constant of the page is
+// not a valid Go identifier, or is declared by the generated package (ex:
+// page "Info" with prefix "Page"), as returned by reservedNames.
+func (ctx *Context) checkPageConst(pageName string) error {
+	name := ctx.pageConst(pageName)
+	if !token.IsIdentifier(name) {
+		return ctx.errorf(pageKey(pageName), "page %s: constant %q is not a valid Go identifier", pageName, name)
+	}
+	if what, ok := ctx.reservedNames()[name]; ok {
+		return ctx.errorf(pageKey(pageName), "page %s: constant %q conflicts with %s", pageName, name, what)
+	}
+	return nil
+}
+
+// checkPages checks the pages and their templates.
+// It returns the (sorted) page names, and the (sorted) names of the defined
+// templates used by the pages.
+func (v *validator) checkPages() ([]string, []string) {
+	ctx := v.ctx
+
+	if len(ctx.Pages) == 0 {
+		v.errorf("pages", "no pages found")
+		return nil, nil
+	}
+	pages := collection.NewUniqueStrings()
+	for pageName := range ctx.Pages {
+		pages.Add(pageName)
+	}
+	pages.Sort()
+
+	templates := collection.NewUniqueStrings()
+	for _, pageName := range pages.ToSlice() {
+		if err := ctx.checkPageConst(pageName); err != nil {
+			v.add(SeverityError, err)
+		}
+		templateName := ctx.Pages[pageName].Template
+		if templateName == "" {
+			v.errorf(pageKey(pageName), "page %s: template not given", pageName)
+			continue
+		}
+		if _, ok := ctx.Templates[templateName]; !ok {
+			v.errorf(pageKey(pageName), "page %s: template %q not defined", pageName, templateName)
+			continue
+		}
+		templates.Add(templateName)
+	}
+	templates.Sort()
+	return pages.ToSlice(), templates.ToSlice()
+}
+
+// checkTemplates checks the given templates: the included templates, the
+// glob patterns, the files and, with the embed asset manager, their paths.
+// It reports the templates that are not used
+// by any page, neither directly nor included in other templates.
+// It returns the mapping from template name to files of the templates that
+// are resolved without errors.
+func (v *validator) checkTemplates(templateNames []string) map[string][]string {
+	ctx := v.ctx
+
+	t2af := map[string][]string{}
+	// mapping from missing file to the templates that use it
+	missing := map[string][]string{}
+	for _, name := range templateNames {
+		m, err := ctx.resolveTemplates([]string{name})
+		if err != nil {
+			v.add(SeverityError, err)
+			continue
+		}
+		files := m[name]
+		ok := true
+		for _, file := range files {
+			if ctx.AssetManager.IsEmbed() {
+				if err := ctx.embedPathError(file); err != nil {
+					v.errorf(templateKey(name), "template %s: file %s cannot be embedded: %s", name, file, err.Error())
+				}
+			}
+			if _, err := os.Stat(ctx.templateFilePath(file)); err != nil {
+				missing[file] = append(missing[file], name)
+				ok = false
+			}
+		}
+		if ok {
+			t2af[name] = files
+		}
+	}
+
+	// missing files are an error only if the files are read at generation
+	// time
+	files := make([]string, 0, len(missing))
+	for file := range missing {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		key := templateKey(missing[file][0])
+		msg := fmt.Sprintf("file %s not found (templates: %s)", file, strings.Join(missing[file], ", "))
+		if ctx.NoTemplateCheck {
+			v.warningf(key, "%s", msg)
+		} else {
+			v.errorf(key, "%s", msg)
+		}
+	}
+
+	// unused templates
+	used := map[string]struct{}{}
+	var visit func(string)
+	visit = func(name string) {
+		if _, ok := used[name]; ok {
+			return
+		}
+		used[name] = struct{}{}
+		for _, item := range ctx.Templates[name] {
+			if _, ok := ctx.Templates[item]; ok {
+				visit(item)
+			}
+		}
+	}
+	for _, name := range templateNames {
+		visit(name)
+	}
+	names := make([]string, 0, len(ctx.Templates))
+	for name := range ctx.Templates {
+		if _, ok := used[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.warningf(templateKey(name), "template %s is not used by any page", name)
+	}
+
+	return t2af
+}
+
+// checkTemplateKinds checks the TemplateKinds parameter.
+func (v *validator) checkTemplateKinds() {
+	names := make([]string, 0, len(v.ctx.TemplateKinds))
+	for name := range v.ctx.TemplateKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := v.ctx.checkTemplateKind(name); err != nil {
+			v.add(SeverityError, err)
+		}
+	}
+}
+
+// checkPageAttributes checks the data type, import, content type, route and
+// method attributes of the pages.
+// The conflicts between pages are checked only if every page is valid.
+func (v *validator) checkPageAttributes(pageNames []string) {
+	ctx := v.ctx
+
+	validData, validRoutes := true, true
+	for _, pageName := range pageNames {
+		page := ctx.Pages[pageName]
+		if _, _, err := ctx.pageDataTypes([]string{pageName}); err != nil {
+			v.add(SeverityError, err)
+			validData = false
+		}
+		if page.ContentType != "" {
+			if err := checkContentType(page.ContentType); err != nil {
+				v.errorf(pageKey(pageName), "page %s: %s", pageName, err.Error())
+			}
+		}
+		if _, err := ctx.pageRoutes([]string{pageName}); err != nil {
+			v.add(SeverityError, err)
+			validRoutes = false
+		}
+	}
+	if validData {
+		if _, _, err := ctx.pageDataTypes(pageNames); err != nil {
+			v.add(SeverityError, err)
+		}
+	}
+	if validRoutes {
+		if _, err := ctx.pageRoutes(pageNames); err != nil {
+			v.add(SeverityError, err)
+		}
+	}
+}
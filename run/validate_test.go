@@ -0,0 +1,332 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/gentmpl/run/types"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	funcMapDir := t.TempDir()
+	funcMapSrc := `package templates
+
+import "html/template"
+
+var funcMap = template.FuncMap{
+	"upper":   nil,
+	"context": nil,
+}
+`
+	if err := os.WriteFile(filepath.Join(funcMapDir, "func-map.go"), []byte(funcMapSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  *Context
+		want []string
+	}{
+		{
+			name: "ok",
+			ctx: &Context{
+				Pages:     pages,
+				Templates: templates,
+			},
+		},
+		{
+			name: "no pages",
+			ctx: &Context{
+				Templates: templates,
+			},
+			want: []string{
+				"error: no pages found",
+				"warning: template flat is not used by any page",
+				"warning: template inh1 is not used by any page",
+				"warning: template inh2 is not used by any page",
+				"warning: template inhbase is not used by any page",
+			},
+		},
+		{
+			name: "many errors",
+			ctx: &Context{
+				AssetManager: types.AssetManagerGoRice,
+				PackageName:  "my-templates",
+				NoCache:      true,
+				Init:         types.InitModeLazy,
+				Pages: map[string]Page{
+					"Pag1":    {Template: "flat", Base: "page-1"},
+					"Pag2":    {Template: "flat2"},
+					"Pag-3":   {Template: "flat", Base: "page-3"},
+					"Info":    {Template: "flat", Base: "page-1"},
+					"NoTmpl":  {},
+					"Cycle":   {Template: "cycle1"},
+					"Missing": {Template: "missing", ContentType: "text html"},
+					"Route1":  {Template: "flat", Base: "page-1", Route: "/a"},
+					"Route2":  {Template: "flat", Base: "page-2", Method: "GET"},
+				},
+				Templates: map[string][]string{
+					"flat":    templates["flat"],
+					"inh1":    templates["inh1"],
+					"inhbase": templates["inhbase"],
+					"cycle1":  {"cycle2", "a.tmpl"},
+					"cycle2":  {"cycle1", "b.tmpl"},
+					"missing": {"flat/missing.tmpl"},
+				},
+				TemplateKinds: map[string]string{"flat": "xml", "other": "text"},
+			},
+			want: []string{
+				`error: assetManager not supported: "go.rice"`,
+				`error: package_name: "my-templates" is not a valid Go identifier`,
+				`warning: init "lazy" is ignored with no_cache`,
+				`error: page Info: constant "PageInfo" conflicts with an identifier of the generated package`,
+				"error: page NoTmpl: template not given",
+				`error: page Pag-3: constant "PagePag-3" is not a valid Go identifier`,
+				`error: page Pag2: template "flat2" not defined`,
+				"error: template cycle1: found invalid cycle (cycle1)",
+				"error: file flat/missing.tmpl not found (templates: missing)",
+				"warning: template inh1 is not used by any page",
+				"warning: template inhbase is not used by any page",
+				`error: template flat: invalid kind "xml": must be "html" or "text"`,
+				`error: template kind of undefined template "other"`,
+				`error: page Missing: invalid content type "text html": mime: expected slash after first token`,
+				`error: page Route2: method "GET" without route`,
+			},
+		},
+		{
+			name: "conflicts between pages",
+			ctx: &Context{
+				Pages: map[string]Page{
+					"Pag1": {Template: "flat", Base: "page-1", Route: "/a"},
+					"Pag2": {Template: "flat", Base: "page-2", Route: "/a"},
+				},
+				Templates: map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				`error: page Pag2: route "/a" already used by page Pag1`,
+			},
+		},
+		{
+			name: "template files",
+			ctx: &Context{
+				Pages: map[string]Page{
+					"Pag1": {Template: "flat", Base: "page-9"},
+				},
+				Templates: map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				`error: page Pag1: base "page-9" not defined in template flat (files: flat/footer.tmpl, flat/header.tmpl, flat/page1.tmpl, flat/page2and3.tmpl)`,
+			},
+		},
+		{
+			name: "generated identifiers",
+			ctx: &Context{
+				TemplateEnumType: "PageTmpl",
+				Pages: map[string]Page{
+					"Enum":  {Template: "flat", Base: "page-1"},
+					"Tmpl":  {Template: "flat", Base: "page-1"},
+					"Pag1":  {Template: "flat", Base: "page-1"},
+					"Users": {Template: "flat", Base: "page-2", Data: "[]string"},
+				},
+				Templates: map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				`error: page Enum: constant "PageEnum" conflicts with an identifier of the generated package`,
+				`error: page Tmpl: constant "PageTmpl" conflicts with an identifier of the generated package`,
+			},
+		},
+		{
+			name: "render function",
+			ctx: &Context{
+				PageEnumPrefix: "Render",
+				Pages: map[string]Page{
+					"Pag1":  {Template: "flat", Base: "page-1"},
+					"Users": {Template: "flat", Base: "page-2", Data: "[]string"},
+				},
+				Templates: map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				`error: page Users: constant "RenderUsers" conflicts with an identifier of the generated package`,
+			},
+		},
+		{
+			name: "many bad bases",
+			ctx: &Context{
+				Pages: map[string]Page{
+					"P1":   {Template: "flat", Base: "x"},
+					"P2":   {Template: "flat", Base: "y"},
+					"Pag1": {Template: "flat", Base: "page-1"},
+				},
+				Templates: map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				`error: page P1: base "x" not defined in template flat (files: flat/footer.tmpl, flat/header.tmpl, flat/page1.tmpl, flat/page2and3.tmpl)`,
+				`error: page P2: base "y" not defined in template flat (files: flat/footer.tmpl, flat/header.tmpl, flat/page1.tmpl, flat/page2and3.tmpl)`,
+			},
+		},
+		{
+			name: "embed paths",
+			ctx: &Context{
+				AssetManager: types.AssetManagerEmbed,
+				Pages: map[string]Page{
+					"Pag1": {Template: "outside", Base: "page-1"},
+					"Pag2": {Template: "dot", Base: "page-1"},
+				},
+				Templates: map[string][]string{
+					"outside": {"flat", "../shared.tmpl"},
+					"dot":     {"./tmpl/flat/page1.tmpl"},
+					"flat":    templates["flat"],
+				},
+				NoTemplateCheck: true,
+			},
+			want: []string{
+				`error: template dot: file ./tmpl/flat/page1.tmpl cannot be embedded: "./tmpl/flat/page1.tmpl" is not a valid embed path`,
+				`error: template outside: file ../shared.tmpl cannot be embedded: "../shared.tmpl" is outside the package folder`,
+				"warning: file ../shared.tmpl not found (templates: outside)",
+			},
+		},
+		{
+			name: "func map context",
+			ctx: &Context{
+				FuncMap:    "funcMap",
+				PackageDir: funcMapDir,
+				Pages:      map[string]Page{"Pag1": {Template: "flat", Base: "page-1"}},
+				Templates:  map[string][]string{"flat": templates["flat"]},
+			},
+			want: []string{
+				fmt.Sprintf(`error: func_map: funcMap defines the "context" function, that is reserved for ExecuteContext (%s:7:2)`, filepath.Join(funcMapDir, "func-map.go")),
+			},
+		},
+		{
+			name: "missing file without template check",
+			ctx: &Context{
+				Pages: map[string]Page{
+					"Miss": {Template: "missing"},
+				},
+				Templates:       map[string][]string{"missing": {"flat/missing.tmpl"}},
+				NoTemplateCheck: true,
+			},
+			want: []string{
+				"warning: file flat/missing.tmpl not found (templates: missing)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ctx.TemplateBaseDir = templateBaseDir
+			tt.ctx.Dir = dir
+			var got []string
+			for _, d := range tt.ctx.Validate() {
+				got = append(got, string(d.Severity)+": "+d.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate():\ngot  %q\nwant %q", got, tt.want)
+			}
+
+			// Check returns the first error of Validate
+			var wantErr string
+			for _, w := range tt.want {
+				if msg, ok := strings.CutPrefix(w, "error: "); ok {
+					wantErr = msg
+					break
+				}
+			}
+			var gotErr string
+			if err := tt.ctx.Check(); err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != wantErr {
+				t.Errorf("Check() error = %q, want %q", gotErr, wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckGeneratedIdentifiers(t *testing.T) {
+	ctx := &Context{
+		Pages:           map[string]Page{"Enum": {Template: "flat", Base: "page-1"}},
+		Templates:       templates,
+		NoTemplateCheck: true,
+	}
+	want := `page Enum: constant "PageEnum" conflicts with an identifier of the generated package`
+	if err := ctx.WritePackage(io.Discard); err == nil || err.Error() != want {
+		t.Errorf("WritePackage() error = %v, want %q", err, want)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	pos := Position{File: "gentmpl.conf", Line: 14, Column: 1}
+	tests := []struct {
+		diag Diagnostic
+		want string
+	}{
+		{
+			Diagnostic{Severity: SeverityError, Pos: pos, Message: `page Pag2: template "flat2" not defined`},
+			`gentmpl.conf:14:1: page Pag2: template "flat2" not defined`,
+		},
+		{
+			Diagnostic{Severity: SeverityWarning, Pos: pos, Message: "template x is not used by any page"},
+			"gentmpl.conf:14:1: warning: template x is not used by any page",
+		},
+		{
+			Diagnostic{Severity: SeverityError, Message: "no pages found"},
+			"no pages found",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.diag.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeTmplFolder(nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &Context{
+		TemplateBaseDir: templateBaseDir,
+		Dir:             dir,
+		Pages: map[string]Page{
+			"Pag1": {Template: "flat", Base: "page-1"},
+			"Pag2": {Template: "flat2"},
+		},
+		Templates: map[string][]string{
+			"flat":   templates["flat"],
+			"unused": {"flat/page1.tmpl"},
+		},
+		NoTemplateCheck: true,
+		Positions: map[string]Position{
+			"pages.Pag1":       {File: "gentmpl.conf", Line: 13, Column: 1},
+			"pages.Pag2":       {File: "gentmpl.conf", Line: 14, Column: 1},
+			"templates.unused": {File: "shared.conf", Line: 3, Column: 1},
+		},
+	}
+
+	want := `gentmpl.conf:14:1: page Pag2: template "flat2" not defined`
+	if err := ctx.Check(); err == nil || err.Error() != want {
+		t.Errorf("Check() error = %v, want %q", err, want)
+	}
+
+	var got []string
+	for _, d := range ctx.Validate() {
+		got = append(got, d.String())
+	}
+	wantDiags := []string{
+		want,
+		"shared.conf:3:1: warning: template unused is not used by any page",
+	}
+	if !reflect.DeepEqual(got, wantDiags) {
+		t.Errorf("Validate():\ngot  %q\nwant %q", got, wantDiags)
+	}
+}